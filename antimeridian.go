@@ -0,0 +1,118 @@
+package kml
+
+import "math"
+
+// wrap puts a longitude in the range centered on long0
+func wrap(long, long0 float64) float64 {
+	return long0 + math.Remainder(long-long0, 360)
+}
+
+// Recenter wraps longitudes into the range long0-180..long0+180
+func Recenter(x []float64, long0 float64) []float64 {
+	xp := make([]float64, len(x))
+	for i, v := range x {
+		xp[i] = wrap(v, long0)
+	}
+	return xp
+}
+
+// SplitLine wraps a long/lat line into the range centered on long0,
+// breaking it where it crosses the seam at long0+180.
+func SplitLine(x, y []float64, long0 float64) []Path {
+	n := len(x)
+	if n == 0 || n != len(y) {
+		return nil
+	}
+	west, east := long0-180, long0+180
+	paths := []Path{}
+	cur := Path{X: []float64{wrap(x[0], long0)}, Y: []float64{y[0]}}
+	for i := 1; i < n; i++ {
+		prev := cur.X[len(cur.X)-1]
+		next := prev + math.Remainder(x[i]-x[i-1], 360)
+		if next > east || next < west {
+			seam, other := east, west
+			if next < west {
+				seam, other = west, east
+			}
+			t := (seam - prev) / (next - prev)
+			lat := y[i-1] + t*(y[i]-y[i-1])
+			if prev != seam { // unless the last point is on the seam already
+				cur.X = append(cur.X, seam)
+				cur.Y = append(cur.Y, lat)
+			}
+			if len(cur.X) > 1 {
+				paths = append(paths, cur)
+			}
+			cur = Path{X: []float64{other}, Y: []float64{lat}}
+			next += other - seam
+		}
+		cur.X = append(cur.X, next)
+		cur.Y = append(cur.Y, y[i])
+	}
+	if len(cur.X) > 1 {
+		paths = append(paths, cur)
+	}
+	return paths
+}
+
+// SplitPolygon wraps a long/lat polygon ring into the range centered on long0,
+// cutting it into closed pieces where it crosses the seam at long0+180.
+// Rings that go all the way around (like Antarctica) are closed along the pole.
+func SplitPolygon(x, y []float64, long0 float64) []Path {
+	n := len(x)
+	if n < 3 || n != len(y) {
+		return nil
+	}
+	// make the longitudes continuous
+	ux := make([]float64, n)
+	uy := make([]float64, n)
+	copy(uy, y)
+	ux[0] = wrap(x[0], long0)
+	for i := 1; i < n; i++ {
+		ux[i] = ux[i-1] + math.Remainder(x[i]-x[i-1], 360)
+	}
+	if math.Abs(ux[n-1]-ux[0]) > 180 {
+		pole := 90.0
+		if mean(uy) < 0 {
+			pole = -90
+		}
+		ux = append(ux, ux[n-1], ux[0])
+		uy = append(uy, pole, pole)
+	}
+	minx, maxx := ux[0], ux[0]
+	for _, v := range ux {
+		minx = math.Min(minx, v)
+		maxx = math.Max(maxx, v)
+	}
+	west := long0 - 180
+	if minx >= west && maxx <= west+360 {
+		return []Path{{X: ux, Y: uy}}
+	}
+	// cut into 360 degree strips, shifting each back into range
+	paths := []Path{}
+	for k := math.Floor((minx - west) / 360); west+360*k < maxx; k++ {
+		lo := west + 360*k
+		cx, cy := clipEdge(ux, uy, lo, true, true)
+		cx, cy = clipEdge(cx, cy, lo+360, true, false)
+		if len(cx) < 3 {
+			continue
+		}
+		for i := range cx {
+			cx[i] -= 360 * k
+		}
+		paths = append(paths, Path{X: cx, Y: cy})
+	}
+	return paths
+}
+
+// mean returns the average of a slice
+func mean(v []float64) float64 {
+	if len(v) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, x := range v {
+		sum += x
+	}
+	return sum / float64(len(v))
+}
@@ -0,0 +1,33 @@
+package kml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		x     []float64
+		long0 float64
+		want  [][]float64 // the longitudes of each path
+	}{
+		{"no crossing", []float64{10, 20, 30}, 0, [][]float64{{10, 20, 30}}},
+		{"crossing east", []float64{170, 190}, 0, [][]float64{{170, 180}, {-180, -170}}},
+		{"crossing west", []float64{-170, -190}, 0, [][]float64{{-170, -180}, {180, 170}}},
+		{"sample on the seam, east", []float64{170, 180, 190}, 0, [][]float64{{170, 180}, {-180, -170}}},
+		{"sample on the seam, west", []float64{-170, 180, 170}, 0, [][]float64{{-170, -180}, {180, 170}}},
+		{"starting on the seam", []float64{180, 190, 200}, 0, [][]float64{{-180, -170, -160}}},
+		{"recentered", []float64{-100, -80}, 90, [][]float64{{260, 270}, {-90, -80}}},
+	}
+	for _, tt := range tests {
+		y := make([]float64, len(tt.x))
+		got := [][]float64{}
+		for _, p := range SplitLine(tt.x, y, tt.long0) {
+			got = append(got, p.X)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package kml
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	buffersides = 64     // sides of a buffer circle
+	bufferstep  = 100000 // maximum length (meters) of the pieces of a buffered segment
+)
+
+// units are the distance units understood by ParseDistance, in meters
+var units = map[string]float64{
+	"m":          1,
+	"meters":     1,
+	"km":         1000,
+	"kilometers": 1000,
+	"mi":         1609.344,
+	"miles":      1609.344,
+}
+
+// ParseDistance returns the meters in a positive distance like "500m", "5km", "1e3km", "3mi", or "3 miles"
+// (a number alone is in meters)
+func ParseDistance(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	// the number is everything up to the unit (no unit begins with e, so exponents are part of the number)
+	i := strings.IndexFunc(s, func(r rune) bool { return !strings.ContainsRune("0123456789.-+eE", r) })
+	num, unit := s, "m"
+	if i >= 0 {
+		num, unit = s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+	}
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad distance %q", s)
+	}
+	if v <= 0 {
+		return 0, fmt.Errorf("%q: the distance must be positive", s)
+	}
+	m, ok := units[unit]
+	if !ok {
+		return 0, fmt.Errorf("unknown distance unit %q (use m, km, or mi)", unit)
+	}
+	return v * m, nil
+}
+
+// BufferPoint returns the geodesic circle of radius d (meters) around a long/lat point, with n sides
+// (64 if n < 3). The points are geodesically d from the center, so the circle is not
+// squashed at high latitudes; longitudes are continuous around the center (not wrapped at 180).
+// The circle should not reach a pole.
+func BufferPoint(long, lat, d float64, n int) Polygon {
+	if n < 3 {
+		n = buffersides
+	}
+	var r Path
+	for i := 0; i <= n; i++ {
+		x, y := Destination(long, lat, 360*float64(n-i)/float64(n), d) // counterclockwise
+		r.X, r.Y = append(r.X, x), append(r.Y, y)
+	}
+	r.X[n], r.Y[n] = r.X[0], r.Y[0]
+	return Polygon{Outer: r}
+}
+
+// BufferPoints returns the area within d (meters) of any of a set of long/lat points: the union of a circle around each
+func BufferPoints(x, y []float64, d float64) []Polygon {
+	if len(x) != len(y) || d <= 0 {
+		return nil
+	}
+	parts := make([][]Polygon, len(x))
+	for i := range x {
+		parts[i] = []Polygon{BufferPoint(x[i], y[i], d, buffersides)}
+	}
+	return unionAll(parts)
+}
+
+// BufferLine returns the area within d (meters) of a long/lat line: a circle around each point,
+// and a strip along each great circle segment, unioned. Longitudes are made continuous along the line.
+func BufferLine(x, y []float64, d float64) []Polygon {
+	n := len(x)
+	if n == 0 || n != len(y) || d <= 0 {
+		return nil
+	}
+	cx := make([]float64, n) // continuous longitudes
+	parts := [][]Polygon{}
+	for i := 0; i < n; i++ {
+		cx[i] = x[i]
+		if i > 0 {
+			cx[i] = wrap(x[i], cx[i-1])
+		}
+		parts = append(parts, []Polygon{BufferPoint(cx[i], y[i], d, buffersides)})
+		if i > 0 {
+			if s, ok := strip(cx[i-1], y[i-1], cx[i], y[i], d); ok {
+				parts = append(parts, []Polygon{s})
+			}
+		}
+	}
+	return unionAll(parts)
+}
+
+// BufferPolygons returns the area of a set of long/lat polygons, with the area within d (meters) of their boundaries
+func BufferPolygons(ps []Polygon, d float64) []Polygon {
+	parts := [][]Polygon{}
+	for _, p := range ps {
+		if ringlen(p.Outer) < 3 {
+			continue
+		}
+		parts = append(parts, []Polygon{p})
+		for _, r := range append([]Path{p.Outer}, p.Inner...) {
+			parts = append(parts, BufferLine(closed(r.X), closed(r.Y), d))
+		}
+	}
+	return unionAll(parts)
+}
+
+// strip returns the area within d (meters) of the sides of a great circle segment,
+// made of points offset perpendicular to the segment, at pieces of at most bufferstep.
+// It reports false for a segment with no length.
+func strip(long1, lat1, long2, lat2, d float64) (Polygon, bool) {
+	length := Haversine(long1, lat1, long2, lat2)
+	if length == 0 {
+		return Polygon{}, false
+	}
+	n := int(math.Ceil(length / math.Min(d, bufferstep)))
+	var left, right Path
+	for i := 0; i <= n; i++ {
+		x, y := intermediate(long1, lat1, long2, lat2, float64(i)/float64(n))
+		var b float64
+		if i < n {
+			b = Bearing(x, y, long2, lat2)
+		} else {
+			b = Bearing(x, y, long1, lat1) + 180
+		}
+		lx, ly := Destination(x, y, b-90, d)
+		rx, ry := Destination(x, y, b+90, d)
+		left.X, left.Y = append(left.X, wrap(lx, x)), append(left.Y, ly)
+		right.X, right.Y = append(right.X, wrap(rx, x)), append(right.Y, ry)
+	}
+	var r Path
+	r.X, r.Y = right.X, right.Y
+	for i := n; i >= 0; i-- {
+		r.X, r.Y = append(r.X, left.X[i]), append(r.Y, left.Y[i])
+	}
+	r.X, r.Y = append(r.X, r.X[0]), append(r.Y, r.Y[0])
+	return Polygon{Outer: r}, true
+}
+
+// unionAll unions sets of polygons, in pairs, so that each union is of similar sizes
+func unionAll(parts [][]Polygon) []Polygon {
+	if len(parts) == 0 {
+		return nil
+	}
+	for len(parts) > 1 {
+		next := [][]Polygon{}
+		for i := 0; i < len(parts); i += 2 {
+			if i+1 < len(parts) {
+				next = append(next, Union(parts[i], parts[i+1]))
+			} else {
+				next = append(next, parts[i])
+			}
+		}
+		parts = next
+	}
+	return parts[0]
+}
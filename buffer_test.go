@@ -0,0 +1,54 @@
+package kml
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseDistance(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+	}{
+		{"500", 500},
+		{"500m", 500},
+		{"5km", 5000},
+		{"1e3km", 1e6},
+		{"2.5E2 m", 250},
+		{"3 miles", 3 * 1609.344},
+		{" 1.5mi ", 1.5 * 1609.344},
+	}
+	for _, tt := range tests {
+		got, err := ParseDistance(tt.s)
+		if err != nil {
+			t.Errorf("%q: %v", tt.s, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%q: %v meters, want %v", tt.s, got, tt.want)
+		}
+	}
+	for _, s := range []string{"", "km", "-5km", "0", "5e", "5 furlongs"} {
+		if got, err := ParseDistance(s); err == nil {
+			t.Errorf("%q: %v meters, want an error", s, got)
+		}
+	}
+}
+
+func TestBufferPoints(t *testing.T) {
+	// two circles apart, and two overlapping
+	x := []float64{0, 10, 10.5}
+	y := []float64{0, 0, 0}
+	ps := BufferPoints(x, y, 100000)
+	if len(ps) != 2 {
+		t.Fatalf("%d polygons, want 2", len(ps))
+	}
+	circle := Area(BufferPoint(0, 0, 100000, 0))
+	var total float64
+	for _, p := range ps {
+		total += Area(p)
+	}
+	if total <= 2*circle || total >= 3*circle {
+		t.Errorf("area %v, want between %v and %v", total, 2*circle, 3*circle)
+	}
+}
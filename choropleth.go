@@ -0,0 +1,153 @@
+package kml
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ajstarks/kml/classify"
+)
+
+// ReadValues reads CSV data with a header row, returning the numeric values
+// of the value column, keyed by the key column. Rows with non-numeric values are skipped.
+func ReadValues(r io.Reader, key, value string) (map[string]float64, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	ki, vi := -1, -1
+	for i, h := range header {
+		switch strings.TrimSpace(h) {
+		case key:
+			ki = i
+		case value:
+			vi = i
+		}
+	}
+	if ki < 0 || vi < 0 {
+		return nil, fmt.Errorf("columns %q and %q must be in the header", key, value)
+	}
+	data := map[string]float64{}
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return data, err
+		}
+		if ki >= len(rec) || vi >= len(rec) {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(rec[vi]), 64)
+		if err != nil {
+			continue
+		}
+		data[strings.TrimSpace(rec[ki])] = v
+	}
+	return data, nil
+}
+
+// Values returns the values of a map, sorted
+func Values(data map[string]float64) []float64 {
+	v := make([]float64, 0, len(data))
+	for _, x := range data {
+		v = append(v, x)
+	}
+	sort.Float64s(v)
+	return v
+}
+
+// rgb is a color
+type rgb struct {
+	r, g, b float64
+}
+
+// parseRGB reads a color in the form #rrggbb, #rgb or rgb(r,g,b)
+func parseRGB(s string) (rgb, error) {
+	var c rgb
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, "#") && len(s) == 7:
+		var r, g, b uint8
+		if _, err := fmt.Sscanf(s, "#%02x%02x%02x", &r, &g, &b); err != nil {
+			return c, fmt.Errorf("%q: bad color", s)
+		}
+		return rgb{float64(r), float64(g), float64(b)}, nil
+	case strings.HasPrefix(s, "#") && len(s) == 4:
+		return parseRGB("#" + s[1:2] + s[1:2] + s[2:3] + s[2:3] + s[3:4] + s[3:4])
+	case strings.HasPrefix(s, "rgb(") && strings.HasSuffix(s, ")"):
+		f := strings.Split(s[4:len(s)-1], ",")
+		if len(f) != 3 {
+			return c, fmt.Errorf("%q: bad color", s)
+		}
+		v := [3]float64{}
+		for i := range f {
+			var err error
+			if v[i], err = strconv.ParseFloat(strings.TrimSpace(f[i]), 64); err != nil {
+				return c, fmt.Errorf("%q: bad color", s)
+			}
+		}
+		return rgb{v[0], v[1], v[2]}, nil
+	}
+	return c, fmt.Errorf("%q: colors must be #rrggbb or rgb(r,g,b)", s)
+}
+
+// String makes the #rrggbb form of a color
+func (c rgb) String() string {
+	clamp := func(v float64) int { return int(math.Round(math.Max(0, math.Min(255, v)))) }
+	return fmt.Sprintf("#%02x%02x%02x", clamp(c.r), clamp(c.g), clamp(c.b))
+}
+
+// lerp interpolates between two colors
+func lerp(a, b rgb, t float64) rgb {
+	return rgb{a.r + t*(b.r-a.r), a.g + t*(b.g-a.g), a.b + t*(b.b-a.b)}
+}
+
+// ColorRamp makes n colors evenly spaced from one color to another
+// (each in the form #rrggbb or rgb(r,g,b))
+func ColorRamp(from, to string, n int) ([]string, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("%d: the number of colors must be positive", n)
+	}
+	a, err := parseRGB(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := parseRGB(to)
+	if err != nil {
+		return nil, err
+	}
+	colors := make([]string, n)
+	for i := 0; i < n; i++ {
+		t := 0.0
+		if n > 1 {
+			t = float64(i) / float64(n-1)
+		}
+		colors[i] = lerp(a, b, t).String()
+	}
+	return colors, nil
+}
+
+// Choropleth assigns colors to keyed values, given class boundaries (see package classify),
+// with one color for each class
+func Choropleth(data map[string]float64, breaks []float64, colors []string) map[string]string {
+	fill := map[string]string{}
+	if len(colors) == 0 {
+		return fill
+	}
+	for k, v := range data {
+		c := classify.Class(v, breaks)
+		if c >= len(colors) {
+			c = len(colors) - 1
+		}
+		fill[k] = colors[c]
+	}
+	return fill
+}
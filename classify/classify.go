@@ -0,0 +1,169 @@
+// Package classify bins numeric data into classes for choropleth and graduated symbol maps.
+// Each method returns class boundaries, from the minimum to the maximum of the data:
+// n classes have n+1 boundaries, except that Jenks makes at most one class per value.
+package classify
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Classify makes n classes using the named method: equal, quantile, stddev or jenks
+func Classify(method string, v []float64, n int) ([]float64, error) {
+	if len(v) == 0 {
+		return nil, fmt.Errorf("no data to classify")
+	}
+	if n < 1 {
+		return nil, fmt.Errorf("%d: the number of classes must be positive", n)
+	}
+	switch method {
+	case "equal", "equalinterval":
+		return EqualInterval(v, n), nil
+	case "quantile":
+		return Quantile(v, n), nil
+	case "stddev":
+		return StdDev(v, n), nil
+	case "jenks", "natural":
+		return Jenks(v, n), nil
+	default:
+		return nil, fmt.Errorf("%q: unknown classification method", method)
+	}
+}
+
+// sorted returns a sorted copy of the data
+func sorted(v []float64) []float64 {
+	s := append([]float64{}, v...)
+	sort.Float64s(s)
+	return s
+}
+
+// EqualInterval makes n classes of the same width
+func EqualInterval(v []float64, n int) []float64 {
+	s := sorted(v)
+	min, max := s[0], s[len(s)-1]
+	b := make([]float64, n+1)
+	for i := 0; i <= n; i++ {
+		b[i] = min + (max-min)*float64(i)/float64(n)
+	}
+	b[n] = max
+	return b
+}
+
+// Quantile makes n classes with the same number of values in each
+func Quantile(v []float64, n int) []float64 {
+	s := sorted(v)
+	b := make([]float64, n+1)
+	for i := 0; i <= n; i++ {
+		b[i] = s[int(math.Round(float64(i)*float64(len(s)-1)/float64(n)))]
+	}
+	return b
+}
+
+// StdDev makes n classes one standard deviation wide, centered on the mean
+// (with even n, the mean is a boundary). The outer classes extend to the minimum and maximum.
+func StdDev(v []float64, n int) []float64 {
+	s := sorted(v)
+	min, max := s[0], s[len(s)-1]
+	var mean, ss float64
+	for _, x := range s {
+		mean += x
+	}
+	mean /= float64(len(s))
+	for _, x := range s {
+		ss += (x - mean) * (x - mean)
+	}
+	sd := math.Sqrt(ss / float64(len(s)))
+	b := make([]float64, n+1)
+	b[0], b[n] = min, max
+	for i := 1; i < n; i++ {
+		b[i] = math.Max(min, math.Min(max, mean+(float64(i)-float64(n)/2)*sd))
+	}
+	return b
+}
+
+// Jenks makes n classes using Jenks natural breaks (the Fisher–Jenks algorithm),
+// minimizing the variance within each class. With fewer values than classes,
+// n is reduced to the number of values, so there are len(v)+1 boundaries.
+func Jenks(v []float64, n int) []float64 {
+	s := sorted(v)
+	m := len(s)
+	if n > m {
+		n = m
+	}
+	// lower[i][j]: index of the lowest value in the last class, for the first i values in j classes
+	lower := make([][]int, m+1)
+	variance := make([][]float64, m+1)
+	for i := range lower {
+		lower[i] = make([]int, n+1)
+		variance[i] = make([]float64, n+1)
+		for j := range variance[i] {
+			variance[i][j] = math.Inf(1)
+		}
+	}
+	for j := 1; j <= n; j++ {
+		lower[1][j] = 1
+		variance[1][j] = 0
+	}
+	for l := 2; l <= m; l++ {
+		var sum, sumsq, w float64
+		for k := 1; k <= l; k++ {
+			i := l - k + 1 // the lowest value in the last class
+			x := s[i-1]
+			w++
+			sum += x
+			sumsq += x * x
+			vc := sumsq - sum*sum/w
+			if i > 1 {
+				for j := 2; j <= n; j++ {
+					if t := vc + variance[i-1][j-1]; t <= variance[l][j] {
+						lower[l][j] = i
+						variance[l][j] = t
+					}
+				}
+			}
+		}
+		lower[l][1] = 1
+		variance[l][1] = sumsq - sum*sum/w
+	}
+	b := make([]float64, n+1)
+	b[0], b[n] = s[0], s[m-1]
+	k := m
+	for j := n; j > 1; j-- {
+		id := lower[k][j] - 1
+		b[j-1] = s[id]
+		k = id
+	}
+	return b
+}
+
+// Manual makes classes from the given interior boundaries, adding the minimum and maximum of the data
+func Manual(v []float64, breaks []float64) ([]float64, error) {
+	if len(v) == 0 {
+		return nil, fmt.Errorf("no data to classify")
+	}
+	s := sorted(v)
+	b := []float64{s[0]}
+	for _, x := range sorted(breaks) {
+		if x > s[0] && x < s[len(s)-1] {
+			b = append(b, x)
+		}
+	}
+	return append(b, s[len(s)-1]), nil
+}
+
+// Class returns the class of a value, given class boundaries.
+// Each class includes its lower boundary; the last class also includes the maximum.
+// Values below the first boundary are in the first class, and above the last in the last class.
+func Class(v float64, breaks []float64) int {
+	n := len(breaks) - 1
+	for i := 1; i < n; i++ {
+		if v < breaks[i] {
+			return i - 1
+		}
+	}
+	if n < 1 {
+		return 0
+	}
+	return n - 1
+}
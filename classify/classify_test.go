@@ -0,0 +1,65 @@
+package classify
+
+import (
+	"math"
+	"testing"
+)
+
+// equal reports whether two sets of boundaries are the same, within a small tolerance
+func equal(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMethods(t *testing.T) {
+	tests := []struct {
+		name   string
+		method func([]float64, int) []float64
+		v      []float64
+		n      int
+		want   []float64
+	}{
+		{"equal", EqualInterval, []float64{0, 3, 1, 10}, 2, []float64{0, 5, 10}},
+		{"equal constant", EqualInterval, []float64{4, 4, 4}, 3, []float64{4, 4, 4, 4}},
+		{"equal n > len", EqualInterval, []float64{0, 8}, 4, []float64{0, 2, 4, 6, 8}},
+		{"quantile", Quantile, []float64{5, 1, 4, 2, 3}, 2, []float64{1, 3, 5}},
+		{"quantile constant", Quantile, []float64{4, 4, 4}, 2, []float64{4, 4, 4}},
+		{"quantile n > len", Quantile, []float64{1, 9}, 4, []float64{1, 1, 9, 9, 9}},
+		{"jenks", Jenks, []float64{1, 2, 3, 10, 11, 12, 30, 31}, 3, []float64{1, 10, 30, 31}},
+		{"jenks constant", Jenks, []float64{4, 4, 4}, 2, []float64{4, 4, 4}},
+		{"jenks n > len", Jenks, []float64{7, 1, 4}, 5, []float64{1, 4, 7, 7}},
+		{"jenks one value", Jenks, []float64{5}, 3, []float64{5, 5}},
+	}
+	for _, tt := range tests {
+		if got := tt.method(tt.v, tt.n); !equal(got, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		v      []float64
+		n      int
+	}{
+		{"empty equal", "equal", nil, 3},
+		{"empty quantile", "quantile", []float64{}, 3},
+		{"empty jenks", "jenks", nil, 3},
+		{"no classes", "jenks", []float64{1, 2}, 0},
+		{"unknown method", "median", []float64{1, 2}, 2},
+	}
+	for _, tt := range tests {
+		if _, err := Classify(tt.method, tt.v, tt.n); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
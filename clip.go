@@ -0,0 +1,129 @@
+package kml
+
+// inside reports whether a point is on the kept side of an axis-aligned line.
+// The line is x=v if vertical is true, otherwise y=v; above keeps the greater side.
+func inside(x, y, v float64, vertical, above bool) bool {
+	c := y
+	if vertical {
+		c = x
+	}
+	if above {
+		return c >= v
+	}
+	return c <= v
+}
+
+// cross returns where the segment x1,y1 - x2,y2 meets an axis-aligned line
+func cross(x1, y1, x2, y2, v float64, vertical bool) (float64, float64) {
+	if vertical {
+		t := (v - x1) / (x2 - x1)
+		return v, y1 + t*(y2-y1)
+	}
+	t := (v - y1) / (y2 - y1)
+	return x1 + t*(x2-x1), v
+}
+
+// clipEdge clips a polygon to one side of an axis-aligned line (Sutherland–Hodgman)
+func clipEdge(x, y []float64, v float64, vertical, above bool) ([]float64, []float64) {
+	n := len(x)
+	xp := []float64{}
+	yp := []float64{}
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		in1 := inside(x[i], y[i], v, vertical, above)
+		in2 := inside(x[j], y[j], v, vertical, above)
+		if in1 {
+			xp = append(xp, x[i])
+			yp = append(yp, y[i])
+		}
+		if in1 != in2 {
+			cx, cy := cross(x[i], y[i], x[j], y[j], v, vertical)
+			xp = append(xp, cx)
+			yp = append(yp, cy)
+		}
+	}
+	return xp, yp
+}
+
+// ClipPolygon clips a polygon in canvas coordinates to the canvas window of g,
+// cutting filled shapes cleanly at the border (Sutherland–Hodgman)
+func ClipPolygon(x, y []float64, g Geometry) ([]float64, []float64) {
+	if len(x) != len(y) {
+		return nil, nil
+	}
+	x, y = clipEdge(x, y, g.Xmin, true, true)
+	x, y = clipEdge(x, y, g.Xmax, true, false)
+	x, y = clipEdge(x, y, g.Ymin, false, true)
+	x, y = clipEdge(x, y, g.Ymax, false, false)
+	return x, y
+}
+
+// ClipLine clips a segment in canvas coordinates to the canvas window of g (Liang–Barsky),
+// returning false if no part of the segment is visible
+func ClipLine(x1, y1, x2, y2 float64, g Geometry) (float64, float64, float64, float64, bool) {
+	dx, dy := x2-x1, y2-y1
+	p := [4]float64{-dx, dx, -dy, dy}
+	q := [4]float64{x1 - g.Xmin, g.Xmax - x1, y1 - g.Ymin, g.Ymax - y1}
+	t0, t1 := 0.0, 1.0
+	for i := 0; i < 4; i++ {
+		if p[i] == 0 {
+			if q[i] < 0 {
+				return 0, 0, 0, 0, false
+			}
+			continue
+		}
+		t := q[i] / p[i]
+		if p[i] < 0 {
+			if t > t1 {
+				return 0, 0, 0, 0, false
+			}
+			if t > t0 {
+				t0 = t
+			}
+		} else {
+			if t < t0 {
+				return 0, 0, 0, 0, false
+			}
+			if t < t1 {
+				t1 = t
+			}
+		}
+	}
+	if t1 < 1 {
+		x2, y2 = x1+t1*dx, y1+t1*dy
+	}
+	if t0 > 0 {
+		x1, y1 = x1+t0*dx, y1+t0*dy
+	}
+	return x1, y1, x2, y2, true
+}
+
+// ClipPath clips an open path in canvas coordinates to the canvas window of g,
+// returning the visible pieces
+func ClipPath(x, y []float64, g Geometry) []Path {
+	n := len(x)
+	if n != len(y) {
+		return nil
+	}
+	paths := []Path{}
+	var cur Path
+	for i := 0; i < n-1; i++ {
+		x1, y1, x2, y2, ok := ClipLine(x[i], y[i], x[i+1], y[i+1], g)
+		if !ok {
+			continue
+		}
+		last := len(cur.X) - 1
+		if last < 0 || cur.X[last] != x1 || cur.Y[last] != y1 {
+			if len(cur.X) > 1 {
+				paths = append(paths, cur)
+			}
+			cur = Path{X: []float64{x1}, Y: []float64{y1}}
+		}
+		cur.X = append(cur.X, x2)
+		cur.Y = append(cur.Y, y2)
+	}
+	if len(cur.X) > 1 {
+		paths = append(paths, cur)
+	}
+	return paths
+}
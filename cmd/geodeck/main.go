@@ -10,13 +10,31 @@ import (
 	"strings"
 
 	"github.com/ajstarks/kml"
+	"github.com/ajstarks/kml/classify"
 )
 
 // config: a bag of configuration options
 type config struct {
-	fulldeck, info, autobbox                                      bool
-	shapesize, textsize                                           float64
+	fulldeck, info, autobbox, declutter, leaders                  bool
+	shapesize, textsize, pad, pagewidth, pageheight, simplify     float64
 	textcolor, color, bbox, shape, bgcolor, style, text, fieldsep string
+	fit, pagesize, simplifyby, simplifyunit                       string
+	ramp, classify, legend, legendtitle                           string
+	minsize, maxsize                                              float64
+	sizeby, colorby, classes                                      int
+	join, joinattr                                                string
+	joined                                                        []kml.Placemark
+	index                                                         *kml.Index
+	hull, hullformat, hullcolor                                   string
+	concavity, bufferdist                                         float64
+	buffer, buffercolor                                           string
+	arcs                                                          bool
+	arcstep                                                       float64
+	flows, flowcurve, flowcolor                                   string
+	flowbend, minwidth, maxwidth                                  float64
+	arrows                                                        bool
+	graticule, graticulesize                                      float64
+	graticulecolor                                                string
 }
 
 // vmap maps one interval to another
@@ -58,7 +76,7 @@ func readData(r io.Reader) ([]float64, []float64, error) {
 	return x, y, s.Err()
 }
 
-// readLoc reads lat/long pairs and an optional name (separated by white space) from a file
+// readLoc reads lat/long pairs, an optional name and label rank (separated by white space) from a file
 func readLoc(r io.Reader, sep byte) (kml.Locdata, error) {
 	var data kml.Locdata
 	s := bufio.NewScanner(r)
@@ -82,6 +100,11 @@ func readLoc(r io.Reader, sep byte) (kml.Locdata, error) {
 		if len(f) > 2 { // if name is present
 			data.Name = append(data.Name, f[2])
 		}
+		if len(f) > 3 { // if label rank is present
+			r, _ := strconv.ParseFloat(f[3], 64)
+			data.Rank = append(data.Rank, r)
+		}
+		data.Fields = append(data.Fields, f)
 	}
 	return data, s.Err()
 }
@@ -144,10 +167,39 @@ func process(filename string, dest io.Writer, c config, mapgeo kml.Geometry) {
 			centerLat, centerLon, maxy, minx, miny, maxx, minx, maxx, miny, maxy)
 		return
 	}
+	// if specified, only tag the points with the attributes of the placemarks containing them
+	if c.index != nil {
+		join(loc.Fields, x, y, c)
+		return
+	}
+	// compute the hull, if specified, and only write it, unless drawing
+	var hx, hy []float64
+	if len(c.hull) > 0 {
+		var err error
+		if hx, hy, err = hull(x, y, c); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return
+		}
+		if c.hullformat != "deck" {
+			writeHull(dest, filename, hx, hy, c)
+			return
+		}
+	}
+	// if specified, connect origin/destination pairs with great circle arcs
+	var arcs []kml.Path
+	if c.arcs {
+		arcs = greatCircles(loc.Fields, c.arcstep)
+		x, y = []float64{}, []float64{}
+		for _, a := range arcs {
+			x, y = append(x, kml.Recenter(a.X, 0)...), append(y, a.Y...)
+		}
+	}
 	// if specified adjust mapping to source data bounding box
 	if c.autobbox {
 		mapgeo.Longmin, mapgeo.Longmax, mapgeo.Latmin, mapgeo.Latmax = bboxData(x, y)
 	}
+	// keep a uniform scale, if specified
+	mapgeo = kml.Fit(mapgeo, c.fit, c.pagewidth, c.pageheight, c.pad)
 	// add slide markup, if specified
 	if c.fulldeck {
 		if len(filename) > 0 {
@@ -159,12 +211,63 @@ func process(filename string, dest io.Writer, c config, mapgeo kml.Geometry) {
 	if len(c.bbox) > 0 {
 		kml.BoundingBox(mapgeo, c.bbox, c.style)
 	}
+	// draw the graticule, if specified
+	if c.graticule > 0 {
+		gr := kml.Grid{Interval: c.graticule, LineWidth: c.shapesize, Color: c.graticulecolor, TextSize: c.graticulesize, TextColor: c.textcolor}
+		kml.DeckGraticule(c.style, gr, mapgeo)
+	}
+	// draw the arcs instead of the data, if specified
+	if c.arcs {
+		drawArcs(arcs, c, mapgeo)
+		if c.fulldeck {
+			endslide(dest, c.style)
+		}
+		return
+	}
+	// simplify the path (not the points), if specified
+	sx, sy := x, y
+	if c.shape != "dot" && c.shape != "circle" && c.simplifyunit == "degrees" {
+		sx, sy = kml.Simplify(c.simplifyby, x, y, c.simplify)
+	}
 	// map to deck canvas, make the drawing
+	sx, sy = kml.MapCoords(sx, sy, mapgeo)
+	if c.shape != "dot" && c.shape != "circle" && c.simplifyunit != "degrees" {
+		sx, sy = kml.Simplify(c.simplifyby, sx, sy, c.simplify)
+	}
+	// draw the hull and buffer under the points, if specified
+	if len(hx) > 0 {
+		hx, hy = kml.MapCoords(hx, hy, mapgeo)
+		kml.Deckshape("polygon", c.style, hx, hy, c.shapesize, c.hullcolor, mapgeo)
+	}
+	if c.bufferdist > 0 {
+		drawBuffer(x, y, c, mapgeo)
+	}
+	// draw the flows between places, if specified
+	if len(c.flows) > 0 {
+		drawFlows(loc, c, mapgeo)
+	}
 	x, y = mapData(x, y, mapgeo)
-	kml.Deckshape(c.shape, c.style, x, y, c.shapesize, c.color, mapgeo)
+	if c.sizeby > 0 {
+		symbols(loc.Fields, x, y, c, mapgeo)
+	} else {
+		kml.Deckshape(c.shape, c.style, sx, sy, c.shapesize, c.color, mapgeo)
+	}
 
 	if len(c.text) > 0 {
-		kml.DeckText(c.text, c.style, x, y, loc.Name, c.textsize, c.textcolor)
+		if c.declutter {
+			labels := []kml.Label{}
+			for i := 0; i < len(x) && i < len(loc.Name); i++ {
+				l := kml.Label{X: x[i], Y: y[i], Text: loc.Name[i]}
+				if i < len(loc.Rank) {
+					l.Rank = loc.Rank[i]
+				}
+				labels = append(labels, l)
+			}
+			placed := kml.PlaceLabels(labels, c.text, c.textsize, mapgeo, c.leaders)
+			kml.DeckLabels(c.style, placed, c.textsize, c.textcolor, mapgeo)
+		} else {
+			kml.DeckText(c.text, c.style, x, y, loc.Name, c.textsize, c.textcolor)
+		}
 	}
 	// end the slide, if specified
 	if c.fulldeck {
@@ -172,6 +275,232 @@ func process(filename string, dest io.Writer, c config, mapgeo kml.Geometry) {
 	}
 }
 
+// hull returns the convex or concave hull of the points,
+// which must include at least three points not in a line to make a polygon
+func hull(x, y []float64, c config) ([]float64, []float64, error) {
+	var hx, hy []float64
+	if c.hull == "concave" {
+		hx, hy = kml.ConcaveHull(x, y, c.concavity)
+	} else {
+		hx, hy = kml.ConvexHull(x, y)
+	}
+	if len(hx) < 4 {
+		return nil, nil, fmt.Errorf("a hull needs at least three points, not in a line")
+	}
+	return hx, hy, nil
+}
+
+// writeHull writes the hull as a KML or GeoJSON polygon, named by the input file
+func writeHull(dest io.Writer, filename string, hx, hy []float64, c config) {
+	name := filename
+	if len(name) == 0 {
+		name = c.hull + " hull"
+	}
+	pm := []kml.Placemark{{Name: name, Polygons: []kml.Polygon{{Outer: kml.Path{X: hx, Y: hy}}}}}
+	var err error
+	if c.hullformat == "geojson" {
+		err = kml.WriteGeoJSON(dest, pm)
+	} else {
+		err = kml.WriteKML(dest, pm)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	}
+}
+
+// greatCircles makes the great circles between origin/destination pairs in rows of lat1, long1, lat2, long2,
+// divided into segments of at most step degrees
+func greatCircles(rows [][]string, step float64) []kml.Path {
+	arcs := []kml.Path{}
+	for _, f := range rows {
+		if len(f) < 4 {
+			continue
+		}
+		v := make([]float64, 4)
+		var err error
+		for i := range v {
+			if v[i], err = strconv.ParseFloat(strings.TrimSpace(f[i]), 64); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			continue
+		}
+		var a kml.Path
+		a.X, a.Y = kml.GreatCircleStep(v[1], v[0], v[3], v[2], step)
+		arcs = append(arcs, a)
+	}
+	return arcs
+}
+
+// drawArcs draws great circle arcs, split where they cross the edge of the map
+func drawArcs(arcs []kml.Path, c config, mapgeo kml.Geometry) {
+	long0 := (mapgeo.Longmin + mapgeo.Longmax) / 2
+	for _, a := range arcs {
+		for _, p := range kml.SplitLine(a.X, a.Y, long0) {
+			ax, ay := kml.MapCoords(p.X, p.Y, mapgeo)
+			kml.Deckshape("polyline", c.style, ax, ay, c.shapesize, c.color, mapgeo)
+		}
+	}
+}
+
+// drawFlows reads flows between the named places (or coordinates), drawing them with widths scaled by value
+func drawFlows(loc kml.Locdata, c config, mapgeo kml.Geometry) {
+	places := map[string][2]float64{}
+	for i := 0; i < len(loc.Name) && i < len(loc.X); i++ {
+		places[loc.Name[i]] = [2]float64{loc.X[i], loc.Y[i]}
+	}
+	r, err := os.Open(c.flows)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return
+	}
+	defer r.Close()
+	flows, err := kml.ReadFlows(r, places)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return
+	}
+	fs := kml.FlowStyle{Curve: c.flowcurve, Bend: c.flowbend, Arrows: c.arrows}
+	kml.DeckFlows(c.style, flows, kml.FlowWidths(flows, c.minwidth, c.maxwidth), []string{c.flowcolor}, fs, mapgeo)
+}
+
+// drawBuffer draws the area within the buffer distance of the points, line, or polygon (following the shape),
+// split where it crosses the edge of the map
+func drawBuffer(x, y []float64, c config, mapgeo kml.Geometry) {
+	var ps []kml.Polygon
+	switch c.shape {
+	case "dot", "circle":
+		ps = kml.BufferPoints(x, y, c.bufferdist)
+	case "fill", "polygon":
+		ps = kml.BufferPolygons([]kml.Polygon{{Outer: kml.Path{X: x, Y: y}}}, c.bufferdist)
+	default:
+		ps = kml.BufferLine(x, y, c.bufferdist)
+	}
+	long0 := (mapgeo.Longmin + mapgeo.Longmax) / 2
+	for _, p := range ps {
+		r := kml.Keyhole(p) // keeping any holes
+		for _, s := range kml.SplitPolygon(r.X, r.Y, long0) {
+			bx, by := kml.MapCoords(s.X, s.Y, mapgeo)
+			kml.Deckshape("polygon", c.style, bx, by, c.shapesize, c.buffercolor, mapgeo)
+		}
+	}
+}
+
+// join prints each row, followed by the specified attributes of the placemark containing its point
+// (empty if there is none)
+func join(rows [][]string, x, y []float64, c config) {
+	attrs := strings.Split(c.joinattr, ",")
+	for i := 0; i < len(rows) && i < len(x); i++ {
+		f := append([]string{}, rows[i]...)
+		k := c.index.Locate(x[i], y[i])
+		for _, a := range attrs {
+			v := ""
+			if k >= 0 {
+				v = c.joined[k].Attr(strings.TrimSpace(a))
+			}
+			f = append(f, v)
+		}
+		fmt.Println(strings.Join(f, c.fieldsep))
+	}
+}
+
+// readJoin reads the placemarks to join points with, and indexes them
+func readJoin(c *config) error {
+	r, err := os.Open(c.join)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	c.joined, err = kml.ReadPlacemarks(r)
+	if err != nil {
+		return err
+	}
+	c.index = kml.NewIndex(c.joined)
+	return nil
+}
+
+// column returns the numeric values of a column (numbered from 1) of every row,
+// and whether each is valid
+func column(rows [][]string, n int) ([]float64, []bool) {
+	v := make([]float64, len(rows))
+	ok := make([]bool, len(rows))
+	for i, f := range rows {
+		if n < 1 || n > len(f) {
+			continue
+		}
+		var err error
+		v[i], err = strconv.ParseFloat(f[n-1], 64)
+		ok[i] = err == nil
+	}
+	return v, ok
+}
+
+// symbols makes proportional circles sized by one column, and optionally colored by another
+func symbols(rows [][]string, x, y []float64, c config, mapgeo kml.Geometry) {
+	values, valid := column(rows, c.sizeby)
+	sx, sy, v := []float64{}, []float64{}, []float64{}
+	for i := 0; i < len(x) && i < len(values); i++ {
+		if valid[i] {
+			sx, sy, v = append(sx, x[i]), append(sy, y[i]), append(v, values[i])
+		}
+	}
+	if len(v) == 0 {
+		return
+	}
+	colors := []string{c.color}
+	if c.colorby > 0 {
+		cv, cvalid := column(rows, c.colorby)
+		colors = symbolColors(cv, cvalid, valid, c)
+	}
+	kml.DeckSymbols(c.style, sx, sy, kml.SymbolSizes(v, c.minsize, c.maxsize), colors)
+
+	// size legend: the smallest, middle and largest values,
+	// on the same scale as the symbols (which is set by the largest)
+	if len(c.legend) > 0 {
+		minv, maxv, _, _ := kml.Bounds(v, v)
+		lv := []float64{minv, (minv + maxv) / 2, maxv}
+		l := kml.Legend{Title: c.legendtitle, Corner: c.legend, Size: c.textsize, Color: c.textcolor}
+		kml.CircleLegend(l, lv, kml.SymbolSizes(lv, c.minsize, c.maxsize), c.color, c.style, mapgeo)
+	}
+}
+
+// symbolColors classifies the color values of symbols, one color for each symbol drawn
+func symbolColors(v []float64, valid, drawn []bool, c config) []string {
+	cv := []float64{}
+	for i := range v {
+		if valid[i] && drawn[i] {
+			cv = append(cv, v[i])
+		}
+	}
+	colors := []string{}
+	breaks, err := classify.Classify(c.classify, cv, c.classes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return []string{c.color}
+	}
+	ramp, err := kml.Ramp(c.ramp, len(breaks)-1)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return []string{c.color}
+	}
+	for i := range v {
+		if !drawn[i] {
+			continue
+		}
+		color := c.color
+		if valid[i] {
+			k := classify.Class(v[i], breaks)
+			if k >= len(ramp) {
+				k = len(ramp) - 1
+			}
+			color = ramp[k]
+		}
+		colors = append(colors, color)
+	}
+	return colors
+}
+
 // begindeck makes the beginning markup
 func begindeck(dest io.Writer, style string) {
 	if style == "deck" {
@@ -234,12 +563,90 @@ func main() {
 	flag.StringVar(&cfg.bgcolor, "bgcolor", "white", "background color")
 	flag.StringVar(&cfg.fieldsep, "fs", " ", "data field separator")
 	flag.BoolVar(&cfg.fulldeck, "fulldeck", false, "make a full deck")
+	flag.StringVar(&cfg.fit, "fit", "stretch", "contain, cover, stretch")
+	flag.StringVar(&cfg.pagesize, "pagesize", "Letter", "page size (name or WxH), used by fit")
+	flag.Float64Var(&cfg.pad, "pad", 0, "padding around the fitted map (percent)")
 
+	flag.Float64Var(&cfg.simplify, "simplify", 0, "simplification tolerance (0 for none)")
+	flag.StringVar(&cfg.simplifyby, "simplifyby", "dp", "simplification method: dp (Douglas-Peucker), vw (Visvalingam-Whyatt)")
+	flag.StringVar(&cfg.simplifyunit, "simplifyunit", "canvas", "simplification tolerance units: canvas, degrees")
+	flag.BoolVar(&cfg.declutter, "declutter", false, "place labels so they don't overlap (rank is the optional fourth field)")
+	flag.BoolVar(&cfg.leaders, "leaders", false, "use leader lines for labels that don't fit (with declutter)")
+	flag.IntVar(&cfg.sizeby, "sizeby", 0, "column (numbered from 1) to scale circle area by (0 for none)")
+	flag.Float64Var(&cfg.minsize, "minsize", 0.5, "minimum diameter of a proportional symbol")
+	flag.Float64Var(&cfg.maxsize, "maxsize", 5, "diameter of the largest proportional symbol")
+	flag.IntVar(&cfg.colorby, "colorby", 0, "column (numbered from 1) to color proportional symbols by (0 for none)")
+	flag.StringVar(&cfg.ramp, "ramp", "#eff3ff #08519c", "colorby colors (palette name, or two colors: from to)")
+	flag.IntVar(&cfg.classes, "classes", 5, "number of colorby classes")
+	flag.StringVar(&cfg.classify, "classify", "quantile", "colorby classification: equal, quantile, stddev, jenks")
+	flag.StringVar(&cfg.legend, "legend", "", "symbol size legend corner: ul, ur, ll, lr (\"\" for no legend)")
+	flag.StringVar(&cfg.legendtitle, "legendtitle", "", "legend title")
+	flag.StringVar(&cfg.join, "join", "", "only tag each point with the attributes of the containing placemark in a KML file")
+	flag.StringVar(&cfg.joinattr, "joinattr", "name", "attributes to tag points with (comma separated)")
+	flag.StringVar(&cfg.hull, "hull", "", "draw the hull of the points: convex, concave (\"\" for none)")
+	flag.Float64Var(&cfg.concavity, "concavity", 2, "concave hull detail (1 is most detailed, larger is closer to convex)")
+	flag.StringVar(&cfg.hullcolor, "hullcolor", "lightsteelblue:50", "hull color")
+	flag.StringVar(&cfg.hullformat, "hullformat", "deck", "hull output: deck (drawn with the points), kml, geojson")
+	flag.StringVar(&cfg.buffer, "buffer", "", "draw the area within a distance (like 500m, 5km, 3mi) of the data (\"\" for none)")
+	flag.StringVar(&cfg.buffercolor, "buffercolor", "orange:30", "buffer color")
+	flag.BoolVar(&cfg.arcs, "arcs", false, "draw great circle arcs between origin/destination pairs (lat1 long1 lat2 long2)")
+	flag.Float64Var(&cfg.arcstep, "arcstep", 1, "maximum arc segment (degrees)")
+	flag.StringVar(&cfg.flows, "flows", "", "draw flows from a CSV file of from, to, value (places named in the input, or \"lat long\")")
+	flag.StringVar(&cfg.flowcurve, "flowcurve", "bezier", "flow lines: straight, bezier, greatcircle")
+	flag.Float64Var(&cfg.flowbend, "flowbend", 0.2, "bezier flow curvature (fraction of the flow length)")
+	flag.StringVar(&cfg.flowcolor, "flowcolor", "steelblue:70", "flow color")
+	flag.Float64Var(&cfg.minwidth, "minwidth", 0.1, "width of the smallest flow")
+	flag.Float64Var(&cfg.maxwidth, "maxwidth", 2, "width of the largest flow")
+	flag.BoolVar(&cfg.arrows, "arrows", false, "draw arrowheads at the flow destinations")
+	flag.Float64Var(&cfg.graticule, "graticule", 0, "draw meridians and parallels every so many degrees (0 for none)")
+	flag.StringVar(&cfg.graticulecolor, "graticulecolor", "gray:50", "graticule color")
+	flag.Float64Var(&cfg.graticulesize, "graticulesize", 1, "graticule label size (0 for no labels)")
 	flag.Parse()
 
+	var err error
+	cfg.pagewidth, cfg.pageheight, err = kml.PageSize(cfg.pagesize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	if len(cfg.buffer) > 0 {
+		cfg.bufferdist, err = kml.ParseDistance(cfg.buffer)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+	switch cfg.hull {
+	case "", "convex", "concave":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown hull %q (use convex or concave)\n", cfg.hull)
+		os.Exit(1)
+	}
+	switch cfg.flowcurve {
+	case "straight", "bezier", "greatcircle":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown flow curve %q (use straight, bezier, or greatcircle)\n", cfg.flowcurve)
+		os.Exit(1)
+	}
+	switch cfg.hullformat {
+	case "deck", "kml", "geojson":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown hull format %q (use deck, kml, or geojson)\n", cfg.hullformat)
+		os.Exit(1)
+	}
+
+	// read and index the placemarks to join with, if specified
+	if len(cfg.join) > 0 {
+		if err := readJoin(&cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	dest := os.Stdout
-	// don't do any generation if info only
-	if cfg.info {
+	// don't do any generation if info, join, or hull only
+	if cfg.info || cfg.index != nil || (len(cfg.hull) > 0 && cfg.hullformat != "deck") {
 		cfg.fulldeck = false
 	}
 	// add deck markup, if specified
@@ -0,0 +1,184 @@
+package kml
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"unicode/utf8"
+)
+
+const (
+	charwidth = 0.6  // estimated character width, relative to text size
+	ascent    = 1.0  // estimated text height above the baseline, relative to text size
+	descent   = 0.25 // estimated text depth below the baseline, relative to text size
+)
+
+// Label is text placed near a point; labels with a higher Rank are placed first.
+// A zero Size or empty Color uses the size or color given for all labels.
+type Label struct {
+	X, Y  float64
+	Text  string
+	Rank  float64
+	Size  float64
+	Color string
+}
+
+// PlacedLabel is a label at its chosen position
+type PlacedLabel struct {
+	Label
+	Align  string  // text alignment: c (center), l (left), e (end)
+	TX, TY float64 // text position
+	Leader bool    // connect the text to X, Y with a leader line
+}
+
+// box is a rectangle in canvas coordinates
+type box struct {
+	x1, y1, x2, y2 float64
+}
+
+// overlaps reports whether two boxes intersect
+func (a box) overlaps(b box) bool {
+	return a.x1 < b.x2 && b.x1 < a.x2 && a.y1 < b.y2 && b.y1 < a.y2
+}
+
+// within reports whether a box is inside the canvas
+func (a box) within(g Geometry) bool {
+	return a.x1 >= g.Xmin && a.x2 <= g.Xmax && a.y1 >= g.Ymin && a.y2 <= g.Ymax
+}
+
+// textwidth estimates the width of text
+func textwidth(s string, size float64) float64 {
+	return float64(utf8.RuneCountInString(s)) * size * charwidth
+}
+
+// textbox estimates the extent of text, given its position, alignment and size
+func textbox(text, align string, x, y, size float64) box {
+	w := textwidth(text, size)
+	switch align {
+	case "c":
+		x -= w / 2
+	case "e":
+		x -= w
+	}
+	return box{x, y - size*descent, x + w, y + size*ascent}
+}
+
+// candidate is a label position relative to its point
+type candidate struct {
+	align  string
+	dx, dy float64
+}
+
+// candidates returns the positions to try, the preferred alignment first,
+// followed by center, beginning, end, above and below
+func candidates(align string, size float64) []candidate {
+	c := []candidate{}
+	seen := map[candidate]bool{}
+	add := func(a string, dx, dy float64) {
+		k := candidate{a, dx, dy}
+		if !seen[k] {
+			seen[k] = true
+			c = append(c, k)
+		}
+	}
+	for _, a := range []string{align, "c", "b", "e"} {
+		a, dx, dy := textadj(a, size)
+		add(a, dx, dy)
+	}
+	add("c", 0, size*1.25)  // above
+	add("c", 0, -size*1.75) // below
+	return c
+}
+
+// PlaceLabels places labels so that they do not overlap each other or leave the canvas.
+// Labels are placed in order of rank; each tries the preferred alignment (c, b, e),
+// then the other alignments, and positions above and below its point.
+// If none fit, and leaders is true, positions farther away are tried, connected by a leader line;
+// otherwise the label is dropped. Size is the text size (unless a label has its own),
+// used to estimate the extent of the text.
+func PlaceLabels(labels []Label, align string, size float64, g Geometry, leaders bool) []PlacedLabel {
+	order := make([]Label, len(labels))
+	copy(order, labels)
+	sort.SliceStable(order, func(i, j int) bool { return order[i].Rank > order[j].Rank })
+
+	placed := []PlacedLabel{}
+	boxes := []box{}
+	fits := func(b box) bool {
+		if !b.within(g) {
+			return false
+		}
+		for _, o := range boxes {
+			if b.overlaps(o) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, l := range order {
+		size := size
+		if l.Size > 0 {
+			size = l.Size
+		}
+		done := false
+		for _, c := range candidates(align, size) {
+			b := textbox(l.Text, c.align, l.X+c.dx, l.Y+c.dy, size)
+			if fits(b) {
+				placed = append(placed, PlacedLabel{Label: l, Align: c.align, TX: l.X + c.dx, TY: l.Y + c.dy})
+				boxes = append(boxes, b)
+				done = true
+				break
+			}
+		}
+		if done || !leaders {
+			continue
+		}
+		// try farther away, in eight directions
+	leader:
+		for _, d := range []float64{3, 5, 8} {
+			for k := 0; k < 8; k++ {
+				a := float64(k) * math.Pi / 4
+				dx, dy := math.Cos(a)*d*size, math.Sin(a)*d*size
+				align := "c"
+				if dx > size/2 {
+					align = "l"
+				} else if dx < -size/2 {
+					align = "e"
+				}
+				b := textbox(l.Text, align, l.X+dx, l.Y+dy, size)
+				if fits(b) {
+					placed = append(placed, PlacedLabel{Label: l, Align: align, TX: l.X + dx, TY: l.Y + dy, Leader: true})
+					boxes = append(boxes, b)
+					break leader
+				}
+			}
+		}
+	}
+	return placed
+}
+
+// DeckLabels makes deck or decksh markup for placed labels, with any leader lines
+func DeckLabels(style string, labels []PlacedLabel, size float64, color string, g Geometry) {
+	for _, l := range labels {
+		size, color := size, color
+		if l.Size > 0 {
+			size = l.Size
+		}
+		if l.Color != "" {
+			color = l.Color
+		}
+		fill, op := colorop(color)
+		lw := size / 20
+		switch style {
+		case "deck":
+			if l.Leader {
+				deckline(l.X, l.Y, l.TX, l.TY, lw, fill, op, g)
+			}
+			fmt.Printf(labelfmt, l.Align, l.TX, l.TY, size, fill, op, xmlesc(l.Text))
+		case "decksh":
+			if l.Leader {
+				deckshline(l.X, l.Y, l.TX, l.TY, lw, fill, op, g)
+			}
+			fmt.Printf(dshlabelfmt, dshalign(l.Align), l.Text, l.TX, l.TY, size, fill, op)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package kml
+
+import "math"
+
+// edge is a directed segment between exact points
+type edge struct {
+	a, b point
+}
+
+// planararea returns the signed planar area of a ring, positive if counterclockwise
+func planararea(x, y []float64) float64 {
+	var a float64
+	n := len(x)
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		a += x[i]*y[j] - x[j]*y[i]
+	}
+	return a / 2
+}
+
+// ringEdges calls add for every edge of a ring, oriented counterclockwise (or clockwise if ccw is false),
+// skipping repeated points
+func ringEdges(r Path, ccw bool, add func(edge)) {
+	n := ringlen(r)
+	if n < 3 {
+		return
+	}
+	reverse := (planararea(r.X[:n], r.Y[:n]) > 0) != ccw
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		e := edge{point{r.X[i], r.Y[i]}, point{r.X[j], r.Y[j]}}
+		if e.a == e.b {
+			continue
+		}
+		if reverse {
+			e.a, e.b = e.b, e.a
+		}
+		add(e)
+	}
+}
+
+// Merge unions polygons that share borders exactly (vertex for vertex, as in a
+// topologically consistent set like the Census files), removing the borders between them.
+// Dissolve also removes borders that are not quite shared.
+func Merge(ps []Polygon) []Polygon {
+	return assemble(boundary(ps))
+}
+
+// boundary returns the edges of a set of polygons, with the interior on their left,
+// less the borders shared between them (an edge cancels the same edge in the other direction)
+func boundary(ps []Polygon) []edge {
+	count := map[edge]int{}
+	order := []edge{}
+	add := func(e edge) {
+		if count[e] == 0 {
+			order = append(order, e)
+		}
+		count[e]++
+	}
+	for _, p := range ps {
+		ringEdges(p.Outer, true, add)
+		for _, h := range p.Inner {
+			ringEdges(h, false, add)
+		}
+	}
+	edges := []edge{}
+	for _, e := range order {
+		if count[e] > count[edge{e.b, e.a}] {
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+// assemble links directed edges (with the interior on their left) into rings,
+// returning polygons made from the counterclockwise rings, with the clockwise rings as their holes
+func assemble(edges []edge) []Polygon {
+	from := map[point][]int{}
+	for i, e := range edges {
+		from[e.a] = append(from[e.a], i)
+	}
+	used := make([]bool, len(edges))
+	var outers, holes []Path
+	for i := range edges {
+		if used[i] {
+			continue
+		}
+		start := edges[i].a
+		var r Path
+		cur := i
+		for {
+			used[cur] = true
+			e := edges[cur]
+			r.X = append(r.X, e.a.x)
+			r.Y = append(r.Y, e.a.y)
+			if e.b == start {
+				break
+			}
+			cur = nextEdge(e, from[e.b], edges, used)
+			if cur < 0 { // not a closed ring
+				r.X = nil
+				break
+			}
+		}
+		if len(r.X) < 3 {
+			continue
+		}
+		r.X = append(r.X, r.X[0])
+		r.Y = append(r.Y, r.Y[0])
+		if planararea(r.X, r.Y) > 0 {
+			outers = append(outers, r)
+		} else {
+			holes = append(holes, r)
+		}
+	}
+	ps := make([]Polygon, len(outers))
+	for i, o := range outers {
+		ps[i].Outer = o
+	}
+	for _, h := range holes {
+		if k := enclosing(h, outers); k >= 0 {
+			ps[k].Inner = append(ps[k].Inner, h)
+		}
+	}
+	return ps
+}
+
+// nextEdge chooses the unused edge leaving the end of e that turns farthest left,
+// so that rings touching at a point are kept apart
+func nextEdge(e edge, candidates []int, edges []edge, used []bool) int {
+	best, angle := -1, -math.Pi*2
+	dx, dy := e.b.x-e.a.x, e.b.y-e.a.y
+	for _, c := range candidates {
+		if used[c] {
+			continue
+		}
+		ox, oy := edges[c].b.x-edges[c].a.x, edges[c].b.y-edges[c].a.y
+		if a := math.Atan2(dx*oy-dy*ox, dx*ox+dy*oy); a > angle {
+			best, angle = c, a
+		}
+	}
+	return best
+}
+
+// enclosing returns the index of the smallest ring that contains most of the points of a hole
+func enclosing(h Path, outers []Path) int {
+	best, area := -1, 0.0
+	hx1, hx2, hy1, hy2 := Bounds(h.X, h.Y)
+	for i, o := range outers {
+		ox1, ox2, oy1, oy2 := Bounds(o.X, o.Y)
+		if hx1 < ox1 || hx2 > ox2 || hy1 < oy1 || hy2 > oy2 {
+			continue
+		}
+		in := 0
+		for k := range h.X {
+			if inring(h.X[k], h.Y[k], o.X, o.Y) {
+				in++
+			}
+		}
+		if a := planararea(o.X, o.Y); 2*in >= len(h.X) && (best < 0 || a < area) {
+			best, area = i, a
+		}
+	}
+	return best
+}
+
+// dissolvesnap is the distance (in degrees, about 10 m) within which the vertices of neighbouring
+// placemarks are taken to be the same point when dissolving, and the width of the gaps closed between them.
+// Borders drawn separately for each neighbour (as in the world files) differ by more than the overlay grid,
+// leaving slivers and the borders themselves in the union.
+const dissolvesnap = 1e-4
+
+// Dissolve merges the placemarks that have the same value of an attribute into one placemark
+// (in order of first appearance), named by that value, as the union of their polygons.
+// Vertices within dissolvesnap of a vertex of another placemark are moved onto it, and holes narrower than that
+// are dropped, so that borders that are not quite shared are removed as well.
+// The merged placemark keeps the attributes that have the same value in all of its placemarks.
+func Dissolve(pms []Placemark, attr string) []Placemark {
+	index := map[string]int{}
+	groups := [][]Placemark{}
+	for _, pm := range pms {
+		v := pm.Attr(attr)
+		k, ok := index[v]
+		if !ok {
+			k = len(groups)
+			index[v] = k
+			groups = append(groups, nil)
+		}
+		groups[k] = append(groups[k], pm)
+	}
+	out := make([]Placemark, len(groups))
+	for i, g := range groups {
+		data := map[string]string{}
+		for name, v := range g[0].Data {
+			data[name] = v
+		}
+		s := snapper{tol: dissolvesnap, cells: map[[2]int64][]owned{}}
+		parts := [][]Polygon{}
+		for k, pm := range g {
+			for name, v := range data {
+				if pm.Data[name] != v {
+					delete(data, name)
+				}
+			}
+			ps := make([]Polygon, len(pm.Polygons))
+			for j, p := range pm.Polygons {
+				ps[j].Outer = s.path(p.Outer, k)
+				for _, h := range p.Inner {
+					ps[j].Inner = append(ps[j].Inner, s.path(h, k))
+				}
+			}
+			parts = append(parts, ps)
+		}
+		data[attr] = g[0].Attr(attr)
+		out[i] = Placemark{Name: data[attr], Data: data, Polygons: unslivered(unionAll(parts), dissolvesnap)}
+	}
+	return out
+}
+
+// owned is a point, and the placemark it came from
+type owned struct {
+	p     point
+	owner int
+}
+
+// snapper moves points onto the nearest point of another owner within a tolerance,
+// indexing the points it has seen in a grid of that size
+type snapper struct {
+	tol   float64
+	cells map[[2]int64][]owned
+}
+
+// snap returns the nearest point of another owner within the tolerance (or the point itself), and remembers it
+func (s *snapper) snap(p point, owner int) point {
+	cx, cy := int64(math.Floor(p.x/s.tol)), int64(math.Floor(p.y/s.tol))
+	best, d := p, s.tol
+	for i := cx - 1; i <= cx+1; i++ {
+		for j := cy - 1; j <= cy+1; j++ {
+			for _, q := range s.cells[[2]int64{i, j}] {
+				if q.owner == owner {
+					continue
+				}
+				if dq := math.Hypot(q.p.x-p.x, q.p.y-p.y); dq <= d {
+					best, d = q.p, dq
+				}
+			}
+		}
+	}
+	key := [2]int64{cx, cy}
+	s.cells[key] = append(s.cells[key], owned{p, owner})
+	return best
+}
+
+// path returns a copy of a path with its points snapped
+func (s *snapper) path(r Path, owner int) Path {
+	sp := Path{X: make([]float64, len(r.X)), Y: make([]float64, len(r.Y))}
+	for i := range r.X {
+		p := s.snap(point{r.X[i], r.Y[i]}, owner)
+		sp.X[i], sp.Y[i] = p.x, p.y
+	}
+	return sp
+}
+
+// unslivered drops the holes of polygons narrower than a width
+// (a sliver of that width has about width/2 as much area as perimeter)
+func unslivered(ps []Polygon, width float64) []Polygon {
+	for i, p := range ps {
+		holes := []Path{}
+		for _, h := range p.Inner {
+			if math.Abs(planararea(h.X, h.Y)) > width*planarlen(h)/2 {
+				holes = append(holes, h)
+			}
+		}
+		ps[i].Inner = holes
+	}
+	return ps
+}
+
+// planarlen returns the planar length of a path
+func planarlen(r Path) float64 {
+	var d float64
+	for i := 1; i < len(r.X); i++ {
+		d += math.Hypot(r.X[i]-r.X[i-1], r.Y[i]-r.Y[i-1])
+	}
+	return d
+}
@@ -0,0 +1,61 @@
+package kml
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDissolveNearlySharedBorder(t *testing.T) {
+	// two neighbours whose border vertices are a meter or two apart,
+	// with an extra vertex on one side making a sliver between them
+	west := Placemark{Data: map[string]string{"region": "r", "name": "west"}, Polygons: []Polygon{{Outer: Path{
+		X: []float64{0, 1, 1, 1, 0, 0},
+		Y: []float64{0, 0, 0.5, 1, 1, 0},
+	}}}}
+	east := Placemark{Data: map[string]string{"region": "r", "name": "east"}, Polygons: []Polygon{{Outer: Path{
+		X: []float64{1.00001, 2, 2, 1.00002, 1.00001, 1.00003, 1.00001},
+		Y: []float64{0.00001, 0, 1, 0.99999, 0.50001, 0.25, 0.00001},
+	}}}}
+	for _, pms := range [][]Placemark{{west, east}, {east, west}} {
+		out := Dissolve(pms, "region")
+		if len(out) != 1 {
+			t.Fatalf("%d placemarks, want 1", len(out))
+		}
+		pm := out[0]
+		if pm.Name != "r" || pm.Data["region"] != "r" {
+			t.Errorf("dissolved placemark named %q, region %q, want r", pm.Name, pm.Data["region"])
+		}
+		if _, ok := pm.Data["name"]; ok {
+			t.Errorf("dissolved placemark kept the name attribute, which differs")
+		}
+		if len(pm.Polygons) != 1 {
+			t.Fatalf("%d polygons, want 1", len(pm.Polygons))
+		}
+		if n := len(pm.Polygons[0].Inner); n != 0 {
+			t.Errorf("%d holes, want none", n)
+		}
+		if a := planarPolygonArea(pm.Polygons); math.Abs(a-2) > 1e-3 {
+			t.Errorf("area %v, want 2", a)
+		}
+	}
+}
+
+func TestDissolveKeepsHoles(t *testing.T) {
+	// a ring around a lake, in two halves
+	south := Placemark{Data: map[string]string{"k": "v"}, Polygons: []Polygon{{
+		Outer: Path{X: []float64{0, 4, 4, 3, 3, 1, 1, 0, 0}, Y: []float64{0, 0, 2, 2, 1, 1, 2, 2, 0}},
+	}}}
+	north := Placemark{Data: map[string]string{"k": "v"}, Polygons: []Polygon{{
+		Outer: Path{X: []float64{0, 1, 1, 3, 3, 4, 4, 0, 0}, Y: []float64{2, 2, 3, 3, 2, 2, 4, 4, 2}},
+	}}}
+	out := Dissolve([]Placemark{south, north}, "k")
+	if len(out) != 1 || len(out[0].Polygons) != 1 {
+		t.Fatalf("want 1 placemark of 1 polygon")
+	}
+	if n := len(out[0].Polygons[0].Inner); n != 1 {
+		t.Errorf("%d holes, want the lake", n)
+	}
+	if a := planarPolygonArea(out[0].Polygons); math.Abs(a-12) > 1e-9 {
+		t.Errorf("area %v, want 12", a)
+	}
+}
@@ -0,0 +1,114 @@
+package kml
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand"
+)
+
+// maxtries limits the random points tried for each dot
+const maxtries = 1000
+
+// DotDensity returns n random points (long, lat) inside a set of polygons (and not in their holes).
+// Dots are shared among the polygons by area, and are evenly distributed over the sphere.
+// Polygons crossing the antimeridian are sampled with continuous longitudes, and their dots wrapped to -180..180.
+// The same seed gives the same points. It also returns the number of dots that could not be placed,
+// when maxtries random points all missed a (very thin) polygon.
+func DotDensity(ps []Polygon, n int, seed int64) ([]float64, []float64, int) {
+	x, y := []float64{}, []float64{}
+	if n <= 0 || len(ps) == 0 {
+		return x, y, 0
+	}
+	rng := rand.New(rand.NewSource(seed))
+	areas := make([]float64, len(ps))
+	unwrapped := make([]Polygon, len(ps))
+	total := 0.0
+	for i, p := range ps {
+		areas[i] = Area(p)
+		unwrapped[i] = unwrap(p)
+		total += areas[i]
+	}
+	missed := 0
+	for i := 0; i < n; i++ {
+		p := unwrapped[pick(rng, areas, total)]
+		minx, maxx, miny, maxy := Bounds(p.Outer.X, p.Outer.Y)
+		// uniform in longitude and sin(latitude) is uniform on the sphere
+		s1, s2 := math.Sin(miny*deg2rad), math.Sin(maxy*deg2rad)
+		t := 0
+		for ; t < maxtries; t++ {
+			px := minx + rng.Float64()*(maxx-minx)
+			py := math.Asin(s1+rng.Float64()*(s2-s1)) * rad2deg
+			if contains(p, px, py) {
+				x = append(x, wrap(px, 0))
+				y = append(y, py)
+				break
+			}
+		}
+		if t == maxtries {
+			missed++
+		}
+	}
+	return x, y, missed
+}
+
+// unwrap makes the longitudes of a polygon continuous (not wrapped at 180), following its outer ring.
+// Rings going around a pole are left as they are.
+func unwrap(p Polygon) Polygon {
+	n := len(p.Outer.X)
+	if n == 0 {
+		return p
+	}
+	long0 := p.Outer.X[0]
+	ring := func(r Path) Path {
+		ux := make([]float64, len(r.X))
+		for i, v := range r.X {
+			if i == 0 {
+				ux[i] = wrap(v, long0)
+			} else {
+				ux[i] = ux[i-1] + math.Remainder(v-r.X[i-1], 360)
+			}
+		}
+		return Path{X: ux, Y: r.Y}
+	}
+	u := Polygon{Outer: ring(p.Outer)}
+	if math.Abs(u.Outer.X[n-1]-u.Outer.X[0]) > 180 {
+		return p
+	}
+	for _, h := range p.Inner {
+		u.Inner = append(u.Inner, ring(h))
+	}
+	return u
+}
+
+// pick chooses an index at random, weighted by value
+func pick(rng *rand.Rand, w []float64, total float64) int {
+	if total <= 0 {
+		return rng.Intn(len(w))
+	}
+	r := rng.Float64() * total
+	for i, v := range w {
+		if r < v {
+			return i
+		}
+		r -= v
+	}
+	return len(w) - 1
+}
+
+// DeckDots makes deck or decksh markup for dots on the canvas, dropping any outside it
+func DeckDots(style string, x, y []float64, size float64, color string, g Geometry) {
+	x, y = filter(x, y, g)
+	switch style {
+	case "deck":
+		DeckPoint(x, y, color, size)
+	case "decksh":
+		DeckshPoint(x, y, color, size)
+	}
+}
+
+// Seed makes a seed for reproducible random numbers from a base seed and a string (like a placemark name)
+func Seed(base int64, s string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return base ^ int64(h.Sum64())
+}
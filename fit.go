@@ -0,0 +1,86 @@
+package kml
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// pagesizes are common deck page sizes in points (width x height)
+var pagesizes = map[string][2]float64{
+	"letter":     {792, 612},
+	"legal":      {1008, 612},
+	"tabloid":    {1224, 792},
+	"archa":      {864, 648},
+	"widescreen": {1152, 648},
+	"4r":         {432, 288},
+	"index":      {360, 216},
+	"a2":         {1684, 1190},
+	"a3":         {1190, 842},
+	"a4":         {842, 595},
+	"a5":         {595, 421},
+}
+
+// PageSize returns the width and height of a page, specified by name (Letter, A4, ...) or as WxH
+func PageSize(s string) (float64, float64, error) {
+	if p, ok := pagesizes[strings.ToLower(s)]; ok {
+		return p[0], p[1], nil
+	}
+	f := strings.Split(strings.ToLower(s), "x")
+	if len(f) != 2 {
+		return 0, 0, fmt.Errorf("%q: page size must be a name or WxH", s)
+	}
+	w, err := strconv.ParseFloat(f[0], 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	h, err := strconv.ParseFloat(f[1], 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	if w <= 0 || h <= 0 {
+		return 0, 0, fmt.Errorf("%q: page size must be positive", s)
+	}
+	return w, h, nil
+}
+
+// Fit adjusts the long/lat range of g so that the map has a uniform scale on a page of width w and height h.
+// Longitude is scaled by the cosine of the center latitude, and the extent is centered on the canvas,
+// with pad (percent of the page) around it. The mode is one of:
+//
+//	contain: show the whole extent, widening the range in one direction
+//	cover:   fill the canvas, narrowing the range in one direction
+//	stretch: map each range to the canvas independently (no change)
+func Fit(g Geometry, mode string, w, h, pad float64) Geometry {
+	if mode != "contain" && mode != "cover" {
+		return g
+	}
+	clong := (g.Longmin + g.Longmax) / 2
+	clat := (g.Latmin + g.Latmax) / 2
+	k := math.Max(math.Cos(clat*deg2rad), 1e-6)
+	ew := (g.Longmax - g.Longmin) * k // projected extent
+	eh := g.Latmax - g.Latmin
+	cw := (g.Xmax-g.Xmin)*w/100 - 2*pad*w/100 // available canvas, in points
+	ch := (g.Ymax-g.Ymin)*h/100 - 2*pad*h/100
+	if cw <= 0 || ch <= 0 || (ew <= 0 && eh <= 0) {
+		return g
+	}
+	// points per projected degree
+	sx, sy := cw/ew, ch/eh
+	var s float64
+	if mode == "contain" {
+		s = math.Min(sx, sy)
+	} else {
+		s = math.Max(sx, sy)
+		if ew <= 0 || eh <= 0 {
+			s = math.Min(sx, sy)
+		}
+	}
+	// the full canvas (with padding) at the uniform scale
+	hw := ((g.Xmax - g.Xmin) * w / 200) / s / k
+	hh := ((g.Ymax - g.Ymin) * h / 200) / s
+	g.Longmin, g.Longmax = clong-hw, clong+hw
+	g.Latmin, g.Latmax = clat-hh, clat+hh
+	return g
+}
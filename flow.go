@@ -0,0 +1,218 @@
+package kml
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	flowsegments = 24  // segments of a bezier flow
+	flowstep     = 1.0 // maximum segment (degrees) of a great circle flow
+)
+
+// Flow is a volume moving from one long/lat point to another
+type Flow struct {
+	From, To                         string
+	FromLong, FromLat, ToLong, ToLat float64
+	Value                            float64
+}
+
+// FlowStyle is how to draw flows
+type FlowStyle struct {
+	Curve  string  // "straight", "bezier" (bending to the right of the direction of flow), or "greatcircle"
+	Bend   float64 // bezier curvature: the offset of the control point, as a fraction of the flow length
+	Arrows bool    // arrowheads at the destinations
+}
+
+// ReadFlows reads CSV data with a header row, including the columns from, to, and value.
+// The from and to places are looked up in places (long, lat, by name), or may be
+// coordinates ("lat long"). Rows with non-numeric values are skipped.
+func ReadFlows(r io.Reader, places map[string][2]float64) ([]Flow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	fi, ti, vi := -1, -1, -1
+	for i, h := range header {
+		switch strings.ToLower(strings.TrimSpace(h)) {
+		case "from":
+			fi = i
+		case "to":
+			ti = i
+		case "value":
+			vi = i
+		}
+	}
+	if fi < 0 || ti < 0 || vi < 0 {
+		return nil, fmt.Errorf("columns \"from\", \"to\" and \"value\" must be in the header")
+	}
+	flows := []Flow{}
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return flows, err
+		}
+		if fi >= len(rec) || ti >= len(rec) || vi >= len(rec) {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(rec[vi]), 64)
+		if err != nil {
+			continue
+		}
+		f := Flow{From: strings.TrimSpace(rec[fi]), To: strings.TrimSpace(rec[ti]), Value: v}
+		var ok bool
+		if f.FromLong, f.FromLat, ok = place(f.From, places); !ok {
+			return flows, fmt.Errorf("unknown place %q", f.From)
+		}
+		if f.ToLong, f.ToLat, ok = place(f.To, places); !ok {
+			return flows, fmt.Errorf("unknown place %q", f.To)
+		}
+		flows = append(flows, f)
+	}
+	return flows, nil
+}
+
+// place returns the long/lat of a named place, or of coordinates ("lat long")
+func place(s string, places map[string][2]float64) (float64, float64, bool) {
+	if p, ok := places[s]; ok {
+		return p[0], p[1], true
+	}
+	f := strings.Fields(s)
+	if len(f) != 2 {
+		return 0, 0, false
+	}
+	lat, err1 := strconv.ParseFloat(f[0], 64)
+	long, err2 := strconv.ParseFloat(f[1], 64)
+	return long, lat, err1 == nil && err2 == nil
+}
+
+// FlowWidths scales the values of flows to line widths,
+// in proportion from min (the smallest value) to max (the largest)
+func FlowWidths(flows []Flow, min, max float64) []float64 {
+	widths := make([]float64, len(flows))
+	if len(flows) == 0 {
+		return widths
+	}
+	vmin, vmax := flows[0].Value, flows[0].Value
+	for _, f := range flows {
+		vmin = math.Min(vmin, f.Value)
+		vmax = math.Max(vmax, f.Value)
+	}
+	for i, f := range flows {
+		t := 1.0
+		if vmax > vmin {
+			t = (f.Value - vmin) / (vmax - vmin)
+		}
+		widths[i] = min + t*(max-min)
+	}
+	return widths
+}
+
+// DeckFlows makes deck or decksh markup for flows, given their line widths, and colors
+// (one for each flow, or one for all). Wider flows are drawn first, so that narrower ones are not hidden.
+func DeckFlows(style string, flows []Flow, widths []float64, colors []string, fs FlowStyle, g Geometry) {
+	n := len(flows)
+	if n != len(widths) || len(colors) == 0 {
+		return
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return widths[order[i]] > widths[order[j]] })
+	long0 := (g.Longmin + g.Longmax) / 2
+	for _, i := range order {
+		color := colors[0]
+		if i < len(colors) {
+			color = colors[i]
+		}
+		paths := flowPaths(flows[i], fs, long0, g)
+		for k, p := range paths {
+			x, y := p.X, p.Y
+			arrow := fs.Arrows && k == len(paths)-1 && len(x) > 1
+			length := math.Max(3*widths[i], 1.5) // of the arrowhead
+			if arrow {
+				x, y = trim(x, y, length) // end the line at the base of the arrowhead
+			}
+			switch style {
+			case "deck":
+				Deckpath(x, y, widths[i], color, g)
+			case "decksh":
+				Deckshpath(x, y, widths[i], color, g)
+			}
+			if arrow {
+				arrowhead(style, x[len(x)-1], y[len(y)-1], p.X[len(p.X)-1], p.Y[len(p.Y)-1], length/2, color, g)
+			}
+		}
+	}
+}
+
+// flowPaths returns the canvas paths of a flow: a great circle (split at the edge of the map),
+// a quadratic bezier curve, or a straight line
+func flowPaths(f Flow, fs FlowStyle, long0 float64, g Geometry) []Path {
+	if fs.Curve == "greatcircle" {
+		paths := Arc(f.FromLong, f.FromLat, f.ToLong, f.ToLat, flowstep, long0)
+		for i, p := range paths {
+			paths[i].X, paths[i].Y = MapCoords(p.X, p.Y, g)
+		}
+		return paths
+	}
+	x1, y1 := mapData(wrap(f.FromLong, long0), f.FromLat, g)
+	x2, y2 := mapData(wrap(f.ToLong, long0), f.ToLat, g)
+	if fs.Curve != "bezier" || fs.Bend == 0 {
+		return []Path{{X: []float64{x1, x2}, Y: []float64{y1, y2}}}
+	}
+	// the control point is offset to the right of the midpoint, so flows in opposite directions are apart
+	dx, dy := x2-x1, y2-y1
+	cx, cy := (x1+x2)/2+dy*fs.Bend, (y1+y2)/2-dx*fs.Bend
+	var p Path
+	for i := 0; i <= flowsegments; i++ {
+		t := float64(i) / flowsegments
+		a, b, c := (1-t)*(1-t), 2*(1-t)*t, t*t
+		p.X = append(p.X, a*x1+b*cx+c*x2)
+		p.Y = append(p.Y, a*y1+b*cy+c*y2)
+	}
+	return []Path{p}
+}
+
+// trim shortens a path by a distance from its end
+func trim(x, y []float64, d float64) ([]float64, []float64) {
+	for i := len(x) - 1; i > 0; i-- {
+		seg := math.Hypot(x[i]-x[i-1], y[i]-y[i-1])
+		if seg >= d {
+			t := (seg - d) / seg
+			tx, ty := append(x[:i:i], x[i-1]+t*(x[i]-x[i-1])), append(y[:i:i], y[i-1]+t*(y[i]-y[i-1]))
+			return tx, ty
+		}
+		d -= seg
+	}
+	return x[:1], y[:1]
+}
+
+// arrowhead makes markup for a triangle pointing to x2, y2, with its base (of half width w) at x1, y1
+func arrowhead(style string, x1, y1, x2, y2, w float64, color string, g Geometry) {
+	dx, dy := x2-x1, y2-y1
+	d := math.Hypot(dx, dy)
+	if d == 0 {
+		return
+	}
+	nx, ny := -dy/d*w, dx/d*w
+	x := []float64{x1 + nx, x2, x1 - nx}
+	y := []float64{y1 + ny, y2, y1 - ny}
+	switch style {
+	case "deck":
+		Deckpolygon(x, y, color, g)
+	case "decksh":
+		Deckshpolygon(x, y, color, g)
+	}
+}
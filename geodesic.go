@@ -0,0 +1,76 @@
+package kml
+
+import "math"
+
+// Destination returns the point (long, lat) reached by going a distance in meters along a great circle
+// from a long/lat point, on an initial bearing (degrees clockwise from north)
+func Destination(long, lat, bearing, d float64) (float64, float64) {
+	p1, l1 := lat*deg2rad, long*deg2rad
+	b := bearing * deg2rad
+	s := d / EarthRadius
+	sinp1, cosp1 := math.Sincos(p1)
+	sins, coss := math.Sincos(s)
+	p2 := math.Asin(sinp1*coss + cosp1*sins*math.Cos(b))
+	l2 := l1 + math.Atan2(math.Sin(b)*sins*cosp1, coss-sinp1*math.Sin(p2))
+	return wrap(l2/deg2rad, long), p2 / deg2rad
+}
+
+// Bearing returns the initial bearing (degrees clockwise from north) of the great circle
+// from one long/lat point to another
+func Bearing(long1, lat1, long2, lat2 float64) float64 {
+	p1, p2 := lat1*deg2rad, lat2*deg2rad
+	dl := (long2 - long1) * deg2rad
+	y := math.Sin(dl) * math.Cos(p2)
+	x := math.Cos(p1)*math.Sin(p2) - math.Sin(p1)*math.Cos(p2)*math.Cos(dl)
+	return math.Mod(math.Atan2(y, x)/deg2rad+360, 360)
+}
+
+// intermediate returns the point a fraction f (0..1) of the way along the great circle between two long/lat points
+func intermediate(long1, lat1, long2, lat2, f float64) (float64, float64) {
+	p1, l1 := lat1*deg2rad, long1*deg2rad
+	p2, l2 := lat2*deg2rad, long2*deg2rad
+	d := Haversine(long1, lat1, long2, lat2) / EarthRadius
+	if d == 0 {
+		return long1, lat1
+	}
+	a := math.Sin((1-f)*d) / math.Sin(d)
+	b := math.Sin(f*d) / math.Sin(d)
+	x := a*math.Cos(p1)*math.Cos(l1) + b*math.Cos(p2)*math.Cos(l2)
+	y := a*math.Cos(p1)*math.Sin(l1) + b*math.Cos(p2)*math.Sin(l2)
+	z := a*math.Sin(p1) + b*math.Sin(p2)
+	return wrap(math.Atan2(y, x)/deg2rad, long1), math.Atan2(z, math.Hypot(x, y)) / deg2rad
+}
+
+// GreatCircle returns the points dividing the great circle between two long/lat points into n segments
+// (at least one). Longitudes are continuous along the arc (not wrapped at 180).
+// Antipodal points do not have a single great circle between them.
+func GreatCircle(long1, lat1, long2, lat2 float64, n int) ([]float64, []float64) {
+	if n < 1 {
+		n = 1
+	}
+	x, y := make([]float64, n+1), make([]float64, n+1)
+	x[0], y[0] = long1, lat1
+	for i := 1; i <= n; i++ {
+		lx, ly := intermediate(long1, lat1, long2, lat2, float64(i)/float64(n))
+		x[i], y[i] = wrap(lx, x[i-1]), ly
+	}
+	return x, y
+}
+
+// GreatCircleStep returns the great circle between two long/lat points,
+// divided into segments of at most step degrees of arc
+func GreatCircleStep(long1, lat1, long2, lat2, step float64) ([]float64, []float64) {
+	n := 1
+	if step > 0 {
+		angle := Haversine(long1, lat1, long2, lat2) / EarthRadius / deg2rad
+		n = int(math.Ceil(angle / step))
+	}
+	return GreatCircle(long1, lat1, long2, lat2, n)
+}
+
+// Arc returns the great circle between two long/lat points, divided into segments of at most step degrees,
+// wrapped into the range centered on long0, and split where it crosses the seam at long0+180
+func Arc(long1, lat1, long2, lat2, step, long0 float64) []Path {
+	x, y := GreatCircleStep(long1, lat1, long2, lat2, step)
+	return SplitLine(x, y, long0)
+}
@@ -0,0 +1,155 @@
+package kml
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// graticulestep is the distance (degrees) between the points of graticule lines
+const graticulestep = 1.0
+
+// Grid is how to draw a graticule
+type Grid struct {
+	Interval  float64 // degrees between lines
+	LineWidth float64 // line width
+	Color     string  // line color
+	TextSize  float64 // degree label size (0 for no labels)
+	TextColor string  // degree label color
+}
+
+// Graticule returns the meridians and parallels at multiples of interval degrees within the bounds,
+// as long/lat lines with points every degree, so that they may be curved by a projection
+func Graticule(interval, longmin, longmax, latmin, latmax float64) ([]Path, []Path) {
+	if interval <= 0 {
+		return nil, nil
+	}
+	meridians, parallels := []Path{}, []Path{}
+	for _, long := range multiples(interval, longmin, longmax) {
+		meridians = append(meridians, Path{X: []float64{long, long}, Y: []float64{latmin, latmax}})
+	}
+	for _, lat := range multiples(interval, latmin, latmax) {
+		parallels = append(parallels, Path{X: []float64{longmin, longmax}, Y: []float64{lat, lat}})
+	}
+	for _, lines := range [][]Path{meridians, parallels} {
+		for i, p := range lines {
+			lines[i] = densify(p)
+		}
+	}
+	return meridians, parallels
+}
+
+// multiples returns the multiples of interval from min to max
+func multiples(interval, min, max float64) []float64 {
+	v := []float64{}
+	for k := math.Ceil(min/interval - 1e-9); k*interval <= max+1e-9; k++ {
+		v = append(v, math.Round(k*interval*1e9)/1e9)
+	}
+	return v
+}
+
+// densify adds points along a two point line, every graticulestep degrees
+func densify(p Path) Path {
+	n := int(math.Ceil(math.Max(math.Abs(p.X[1]-p.X[0]), math.Abs(p.Y[1]-p.Y[0])) / graticulestep))
+	if n < 1 {
+		n = 1
+	}
+	var d Path
+	for i := 0; i <= n; i++ {
+		t := float64(i) / float64(n)
+		d.X = append(d.X, p.X[0]+t*(p.X[1]-p.X[0]))
+		d.Y = append(d.Y, p.Y[0]+t*(p.Y[1]-p.Y[0]))
+	}
+	return d
+}
+
+// DeckGraticule makes markup for the graticule of a (long/lat) map,
+// with degree labels along the bottom and left edges
+func DeckGraticule(style string, gr Grid, g Geometry) {
+	meridians, parallels := Graticule(gr.Interval, g.Longmin, g.Longmax, g.Latmin, g.Latmax)
+	for _, p := range append(meridians, parallels...) {
+		x, y := MapCoords(p.X, p.Y, g)
+		gridline(style, x, y, gr, g)
+	}
+	if gr.TextSize <= 0 {
+		return
+	}
+	for _, p := range meridians {
+		if p.X[0] >= g.Longmin+360 { // labeled on the other side
+			continue
+		}
+		x, _ := mapData(p.X[0], 0, g)
+		gridtext(style, "c", degrees(p.X[0], "E", "W"), x, g.Ymin-1.5*gr.TextSize, gr)
+	}
+	for _, p := range parallels {
+		_, y := mapData(0, p.Y[0], g)
+		gridtext(style, "e", degrees(p.Y[0], "N", "S"), g.Xmin-gr.TextSize/2, y-gr.TextSize/3, gr)
+	}
+}
+
+// OrthoGraticule makes markup for the graticule of a globe, with meridians labeled
+// along the equator, and parallels along the central meridian
+func OrthoGraticule(style string, gr Grid, o Ortho, g Geometry) {
+	meridians, parallels := Graticule(gr.Interval, -180, 180, -90, 90)
+	if n := len(meridians); n > 1 && meridians[n-1].X[0] == 180 { // the same as -180
+		meridians = meridians[:n-1]
+	}
+	if n := len(parallels); n > 0 && parallels[n-1].Y[0] == 90 { // the poles are points
+		parallels = parallels[:n-1]
+	}
+	if len(parallels) > 0 && parallels[0].Y[0] == -90 {
+		parallels = parallels[1:]
+	}
+	for _, p := range append(meridians, parallels...) {
+		for _, v := range OrthoPolyline(p.X, p.Y, o, g) {
+			gridline(style, v.X, v.Y, gr, g)
+		}
+	}
+	if gr.TextSize <= 0 {
+		return
+	}
+	for _, p := range meridians {
+		if x, y := OrthoPoints(p.X[:1], []float64{0}, o, g); len(x) > 0 {
+			gridtext(style, "c", degrees(p.X[0], "E", "W"), x[0], y[0]-1.5*gr.TextSize, gr)
+		}
+	}
+	for _, p := range parallels {
+		if x, y := OrthoPoints([]float64{o.Long0}, p.Y[:1], o, g); len(x) > 0 {
+			gridtext(style, "l", degrees(p.Y[0], "N", "S"), x[0]+gr.TextSize/2, y[0]+gr.TextSize/3, gr)
+		}
+	}
+}
+
+// gridline makes markup for a graticule line on the canvas
+func gridline(style string, x, y []float64, gr Grid, g Geometry) {
+	switch style {
+	case "deck", "decksh":
+		Deckshape("path", style, x, y, gr.LineWidth, gr.Color, g)
+	}
+}
+
+// gridtext makes markup for a degree label
+func gridtext(style, align, s string, x, y float64, gr Grid) {
+	fill, op := colorop(gr.TextColor)
+	switch style {
+	case "deck":
+		fmt.Printf(labelfmt, align, x, y, gr.TextSize, fill, op, xmlesc(s))
+	case "decksh":
+		fmt.Printf(dshlabelfmt, dshalign(align), s, x, y, gr.TextSize, fill, op)
+	}
+}
+
+// degrees formats a longitude or latitude as a degree label, like 30°W, 90°S or 180°E (0° has no hemisphere)
+func degrees(v float64, pos, neg string) string {
+	if pos == "E" {
+		v = wrap(v, 0)
+	}
+	s := strconv.FormatFloat(math.Abs(v), 'f', -1, 64) + "°"
+	switch {
+	case v > 0:
+		return s + pos
+	case v < 0:
+		return s + neg
+	}
+	return s
+}
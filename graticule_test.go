@@ -0,0 +1,27 @@
+package kml
+
+import "testing"
+
+func TestDegrees(t *testing.T) {
+	tests := []struct {
+		v        float64
+		pos, neg string
+		want     string
+	}{
+		{0, "N", "S", "0°"},
+		{45, "N", "S", "45°N"},
+		{-30.5, "N", "S", "30.5°S"},
+		{90, "N", "S", "90°N"},
+		{-90, "N", "S", "90°S"},
+		{0, "E", "W", "0°"},
+		{-30, "E", "W", "30°W"},
+		{210, "E", "W", "150°W"},
+		{180, "E", "W", "180°E"},
+		{-180, "E", "W", "180°W"},
+	}
+	for _, tt := range tests {
+		if got := degrees(tt.v, tt.pos, tt.neg); got != tt.want {
+			t.Errorf("degrees(%v, %q, %q) = %q, want %q", tt.v, tt.pos, tt.neg, got, tt.want)
+		}
+	}
+}
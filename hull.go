@@ -0,0 +1,159 @@
+package kml
+
+import (
+	"math"
+	"sort"
+)
+
+// ConvexHull returns the convex hull of a set of points, as a closed counterclockwise ring
+// (monotone chain). Fewer than three distinct points, or points in a line, give fewer
+// than the four positions of a polygon ring.
+func ConvexHull(x, y []float64) ([]float64, []float64) {
+	idx := convexhull(x, y)
+	hx, hy := make([]float64, 0, len(idx)+1), make([]float64, 0, len(idx)+1)
+	for _, i := range idx {
+		hx, hy = append(hx, x[i]), append(hy, y[i])
+	}
+	if len(idx) > 0 {
+		hx, hy = append(hx, x[idx[0]]), append(hy, y[idx[0]])
+	}
+	return hx, hy
+}
+
+// convexhull returns the indexes of the points on the convex hull, counterclockwise
+func convexhull(x, y []float64) []int {
+	n := len(x)
+	if n != len(y) || n == 0 {
+		return nil
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		return x[a] < x[b] || (x[a] == x[b] && y[a] < y[b])
+	})
+	turn := func(o, a, b int) float64 {
+		return (x[a]-x[o])*(y[b]-y[o]) - (y[a]-y[o])*(x[b]-x[o])
+	}
+	hull := []int{}
+	for _, i := range order { // lower
+		for len(hull) >= 2 && turn(hull[len(hull)-2], hull[len(hull)-1], i) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, i)
+	}
+	lower := len(hull) + 1
+	for k := n - 2; k >= 0; k-- { // upper
+		i := order[k]
+		for len(hull) >= lower && turn(hull[len(hull)-2], hull[len(hull)-1], i) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, i)
+	}
+	if len(hull) > 1 {
+		hull = hull[:len(hull)-1] // the last point is the first
+	}
+	return hull
+}
+
+// ConcaveHull returns a concave hull of a set of points, as a closed counterclockwise ring.
+// Starting with the convex hull, edges are dug inward to the nearest point, as long as that point is
+// within the edge length divided by concavity of an end of the edge, and the hull does not cross itself.
+// A concavity of 1 gives a detailed hull, larger values are smoother (approaching the convex hull).
+func ConcaveHull(x, y []float64, concavity float64) ([]float64, []float64) {
+	hull := convexhull(x, y)
+	if len(hull) < 3 || concavity <= 0 {
+		return ConvexHull(x, y)
+	}
+	used := make([]bool, len(x))
+	for _, i := range hull {
+		used[i] = true
+	}
+	// the hull is a ring of point indexes; next links them counterclockwise
+	next := map[int]int{}
+	prev := map[int]int{}
+	for k, i := range hull {
+		j := hull[(k+1)%len(hull)]
+		next[i], prev[j] = j, i
+	}
+	queue := append([]int{}, hull...) // edges, by their first point
+	for len(queue) > 0 {
+		a := queue[0]
+		queue = queue[1:]
+		b := next[a]
+		maxd := math.Hypot(x[b]-x[a], y[b]-y[a]) / concavity
+		p := digpoint(x, y, used, prev[a], a, b, next[b], maxd, next)
+		if p < 0 {
+			continue
+		}
+		used[p] = true
+		next[a], prev[p], next[p], prev[b] = p, a, b, p
+		queue = append(queue, a, p)
+	}
+	start := hull[0]
+	hx, hy := []float64{x[start]}, []float64{y[start]}
+	for i := next[start]; i != start; i = next[i] {
+		hx, hy = append(hx, x[i]), append(hy, y[i])
+	}
+	return append(hx, x[start]), append(hy, y[start])
+}
+
+// digpoint returns the unused point nearest the edge a-b that is nearer this edge than the
+// neighboring edges (pa-a, b-nb), and does not make the new edges cross the hull,
+// if it is within maxd of a or b. Otherwise it returns -1.
+func digpoint(x, y []float64, used []bool, pa, a, b, nb int, maxd float64, next map[int]int) int {
+	minx, maxx := math.Min(x[a], x[b])-maxd, math.Max(x[a], x[b])+maxd
+	miny, maxy := math.Min(y[a], y[b])-maxd, math.Max(y[a], y[b])+maxd
+	type near struct {
+		i int
+		d float64
+	}
+	list := []near{}
+	for i := range x {
+		if used[i] || x[i] < minx || x[i] > maxx || y[i] < miny || y[i] > maxy {
+			continue
+		}
+		d := segdist(x[i], y[i], x[a], y[a], x[b], y[b])
+		if d > segdist(x[i], y[i], x[pa], y[pa], x[a], y[a]) || d > segdist(x[i], y[i], x[b], y[b], x[nb], y[nb]) {
+			continue
+		}
+		list = append(list, near{i, d})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].d < list[j].d })
+	for _, c := range list {
+		if crosses(x, y, a, c.i, a, next) || crosses(x, y, c.i, b, a, next) {
+			continue
+		}
+		if math.Min(math.Hypot(x[c.i]-x[a], y[c.i]-y[a]), math.Hypot(x[c.i]-x[b], y[c.i]-y[b])) > maxd {
+			break
+		}
+		return c.i
+	}
+	return -1
+}
+
+// crosses reports whether the segment p-q crosses an edge of the hull (other than at p or q),
+// going around the hull from start
+func crosses(x, y []float64, p, q, start int, next map[int]int) bool {
+	for i := start; ; {
+		j := next[i]
+		if i != p && i != q && j != p && j != q && segcross(x[p], y[p], x[q], y[q], x[i], y[i], x[j], y[j]) {
+			return true
+		}
+		if i = j; i == start {
+			break
+		}
+	}
+	return false
+}
+
+// segcross reports whether two segments properly intersect
+func segcross(x1, y1, x2, y2, x3, y3, x4, y4 float64) bool {
+	d1 := (x4-x3)*(y1-y3) - (y4-y3)*(x1-x3)
+	d2 := (x4-x3)*(y2-y3) - (y4-y3)*(x2-x3)
+	d3 := (x2-x1)*(y3-y1) - (y2-y1)*(x3-x1)
+	d4 := (x2-x1)*(y4-y1) - (y2-y1)*(x4-x1)
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
@@ -0,0 +1,119 @@
+package kml
+
+import (
+	"math"
+	"sort"
+)
+
+// nodesize is the maximum number of entries in an index node
+const nodesize = 16
+
+// rentry is a bounding box in an index node, with either a child node or a placemark
+type rentry struct {
+	minx, maxx, miny, maxy float64
+	node                   *rnode
+	item                   int
+}
+
+// rnode is a node of the index
+type rnode struct {
+	entries []rentry
+}
+
+// Index is a static R-tree over the bounding boxes of placemarks,
+// packed by Sort-Tile-Recursive, for finding the placemarks at a point
+type Index struct {
+	root *rnode
+	pms  []Placemark
+}
+
+// NewIndex makes an index of placemarks
+func NewIndex(pms []Placemark) *Index {
+	entries := []rentry{}
+	for i, pm := range pms {
+		if len(pm.Polygons) == 0 {
+			continue
+		}
+		minx, maxx, miny, maxy := PolygonBounds(pm.Polygons)
+		entries = append(entries, rentry{minx: minx, maxx: maxx, miny: miny, maxy: maxy, item: i})
+	}
+	for len(entries) > nodesize {
+		entries = pack(entries)
+	}
+	return &Index{root: &rnode{entries: entries}, pms: pms}
+}
+
+// pack groups entries into nodes of neighbors: sorted into vertical slices by x,
+// then into runs by y, returning an entry for each node
+func pack(entries []rentry) []rentry {
+	cx := func(e rentry) float64 { return e.minx + e.maxx }
+	cy := func(e rentry) float64 { return e.miny + e.maxy }
+	sort.Slice(entries, func(i, j int) bool { return cx(entries[i]) < cx(entries[j]) })
+	nodes := (len(entries) + nodesize - 1) / nodesize
+	slices := int(math.Ceil(math.Sqrt(float64(nodes))))
+	per := slices * nodesize
+	parents := []rentry{}
+	for s := 0; s < len(entries); s += per {
+		slice := entries[s:imin(s+per, len(entries))]
+		sort.Slice(slice, func(i, j int) bool { return cy(slice[i]) < cy(slice[j]) })
+		for k := 0; k < len(slice); k += nodesize {
+			n := &rnode{entries: append([]rentry{}, slice[k:imin(k+nodesize, len(slice))]...)}
+			p := rentry{minx: math.Inf(1), maxx: math.Inf(-1), miny: math.Inf(1), maxy: math.Inf(-1), node: n}
+			for _, e := range n.entries {
+				p.minx, p.maxx = math.Min(p.minx, e.minx), math.Max(p.maxx, e.maxx)
+				p.miny, p.maxy = math.Min(p.miny, e.miny), math.Max(p.maxy, e.maxy)
+			}
+			parents = append(parents, p)
+		}
+	}
+	return parents
+}
+
+// imin returns the smaller of two ints
+func imin(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Search returns the (sorted) indexes of the placemarks whose bounding boxes intersect a box
+func (ix *Index) Search(minx, maxx, miny, maxy float64) []int {
+	found := []int{}
+	var search func(n *rnode)
+	search = func(n *rnode) {
+		for _, e := range n.entries {
+			if e.maxx < minx || e.minx > maxx || e.maxy < miny || e.miny > maxy {
+				continue
+			}
+			if e.node != nil {
+				search(e.node)
+			} else {
+				found = append(found, e.item)
+			}
+		}
+	}
+	search(ix.root)
+	sort.Ints(found)
+	return found
+}
+
+// Locate returns the index of the first placemark containing a point (long, lat), or -1 if there is none
+func (ix *Index) Locate(long, lat float64) int {
+	for _, i := range ix.Search(long, long, lat, lat) {
+		if Contains(ix.pms[i].Polygons, long, lat) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Contains reports whether a point is inside any of a set of polygons (and not in a hole)
+func Contains(ps []Polygon, x, y float64) bool {
+	for _, p := range ps {
+		if contains(p, x, y) {
+			return true
+		}
+	}
+	return false
+}
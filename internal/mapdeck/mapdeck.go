@@ -0,0 +1,541 @@
+// Package mapdeck makes maps of the placemarks of KML files: the shared work of the world and usmap commands.
+package mapdeck
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ajstarks/kml"
+	"github.com/ajstarks/kml/classify"
+)
+
+// config: a bag of configuration options
+type config struct {
+	fulldeck, globe, topology, stats, declutter, leaders      bool
+	linewidth, pad, lon0, simplify, textsize                  float64
+	color, bbox, shape, bgcolor, style, center, fit, pagesize string
+	simplifyby, simplifyunit, text, textcolor, rankby         string
+	choropleth, key, value, ramp, classify, breaks            string
+	legend, legendtitle, legendtype                           string
+	dots, dotcolor                                            string
+	dotvalue, dotsize                                         float64
+	graticule, graticulesize                                  float64
+	graticulecolor                                            string
+	seed                                                      int64
+	where                                                     string
+	match                                                     func(kml.Placemark) bool
+	rulefile                                                  string
+	dissolve                                                  string
+	rules                                                     []kml.Rule
+	classes                                                   int
+	classbreaks                                               []float64
+	colors                                                    []string
+	fills                                                     map[string]string
+	ortho                                                     kml.Ortho
+	name                                                      string
+}
+
+// Defaults are the settings that differ between commands
+type Defaults struct {
+	Geometry kml.Geometry // canvas and map extent
+	Key      string       // attribute joining placemarks to the choropleth data
+	Name     string       // attribute naming placemarks
+	Globe    bool         // offer the orthographic globe view
+}
+
+// readData reads the placemarks of a KML file
+func readData(filename string) ([]kml.Placemark, error) {
+	r, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return kml.ReadPlacemarks(r)
+}
+
+// selected returns the placemarks matching the filter, dissolved by an attribute if specified
+func selected(pms []kml.Placemark, c config) []kml.Placemark {
+	pms = kml.Filter(pms, c.match)
+	if len(c.dissolve) > 0 {
+		pms = kml.Dissolve(pms, c.dissolve)
+	}
+	return pms
+}
+
+// kmldeck makes deck or decksh markup from coordinates
+func kmldeck(data []kml.Placemark, m kml.Geometry, c config) {
+	pms := selected(data, c)
+	// simplify shared borders once, if specified
+	if c.topology && c.simplify > 0 {
+		tol := c.simplify
+		if c.simplifyunit != "degrees" {
+			tol *= (m.Latmax - m.Latmin) / (m.Ymax - m.Ymin)
+		}
+		pms = kml.SimplifyShared(c.simplifyby, pms, tol)
+		c.simplify = 0
+	}
+	// for every placemark, fill the polygons, then draw the outlines in the specified shape
+	// (lines, when the shape is a fill)
+	outline := c.shape
+	if outline == "fill" || outline == "polygon" {
+		outline = "polyline"
+	}
+	for _, layer := range []string{"fill", outline} {
+		for _, pm := range pms {
+			st := style(pm, c)
+			pc := c
+			pc.shape = layer
+			switch layer {
+			case "fill":
+				pc.color = st.Fill
+			default:
+				pc.color, pc.linewidth = st.Stroke, st.LineWidth
+			}
+			if pc.color == "" || pc.color == "none" {
+				continue
+			}
+			for _, p := range pm.Polygons {
+				ring(p.Outer, m, pc)
+			}
+		}
+	}
+	// make a dot-density layer, if specified
+	if len(c.dots) > 0 {
+		dots(pms, m, c)
+	}
+	// label the placemarks, if specified
+	if len(c.text) > 0 {
+		labels(pms, m, c)
+	}
+}
+
+// style returns the style of a placemark: the fill or line color (or its choropleth color),
+// overridden by any matching rules
+func style(pm kml.Placemark, c config) kml.Style {
+	color := c.color
+	if f, ok := c.fills[pm.Data[c.key]]; ok { // choropleth color, if specified
+		color = f
+	}
+	s := kml.Style{LineWidth: c.linewidth, TextSize: c.textsize, TextColor: c.textcolor}
+	switch c.shape {
+	case "fill", "polygon":
+		s.Fill = color
+	default:
+		s.Stroke = color
+	}
+	return kml.Apply(c.rules, pm, s)
+}
+
+// labels places the name of every placemark inside its largest polygon,
+// keeping labels apart (in order of rank) if specified
+func labels(pms []kml.Placemark, m kml.Geometry, c config) {
+	list := []kml.Label{}
+	for _, pm := range pms {
+		st := style(pm, c)
+		if len(pm.Polygons) == 0 || st.Label == "none" {
+			continue
+		}
+		text := pmname(pm, c.name)
+		if len(st.Label) > 0 {
+			text = pm.Attr(st.Label)
+		}
+		lx, ly := kml.LabelPoint(pm.Polygons, 0.01)
+		px, py := label(lx, ly, m, c)
+		if len(px) == 0 || px[0] < m.Xmin || px[0] > m.Xmax || py[0] < m.Ymin || py[0] > m.Ymax {
+			continue
+		}
+		list = append(list, kml.Label{X: px[0], Y: py[0], Text: text, Rank: rank(pm, c.rankby), Size: st.TextSize, Color: st.TextColor})
+	}
+	if c.declutter {
+		placed := kml.PlaceLabels(list, c.text, c.textsize, m, c.leaders)
+		kml.DeckLabels(c.style, placed, c.textsize, c.textcolor, m)
+		return
+	}
+	for _, l := range list {
+		kml.DeckText(c.text, c.style, []float64{l.X}, []float64{l.Y}, []string{l.Text}, l.Size, l.Color)
+	}
+}
+
+// dots places a dot for every dotvalue of an attribute, at random inside each placemark
+func dots(pms []kml.Placemark, m kml.Geometry, c config) {
+	if c.dotvalue <= 0 {
+		return
+	}
+	for _, pm := range pms {
+		v, err := strconv.ParseFloat(pm.Data[c.dots], 64)
+		if err != nil {
+			continue
+		}
+		n := int(math.Round(v / c.dotvalue))
+		x, y, missed := kml.DotDensity(pm.Polygons, n, kml.Seed(c.seed, pmname(pm, c.name)))
+		if missed > 0 {
+			fmt.Fprintf(os.Stderr, "%s: %d of %d dots could not be placed\n", pmname(pm, c.name), missed, n)
+		}
+		if c.globe {
+			x, y = kml.OrthoPoints(x, y, c.ortho, m)
+		} else {
+			x, y = kml.MapCoords(kml.Recenter(x, c.lon0), y, m)
+		}
+		kml.DeckDots(c.style, x, y, c.dotsize, c.dotcolor, m)
+	}
+}
+
+// rank returns the label rank of a placemark: the value of an attribute, or its area
+func rank(pm kml.Placemark, attr string) float64 {
+	if len(attr) > 0 {
+		v, _ := strconv.ParseFloat(pm.Data[attr], 64)
+		return v
+	}
+	var a float64
+	for _, p := range pm.Polygons {
+		a += kml.Area(p)
+	}
+	return a
+}
+
+// ring makes markup for a single polygon ring, split at the antimeridian, or on a globe if specified
+func ring(r kml.Path, m kml.Geometry, c config) {
+	x, y := r.X, r.Y
+	if c.simplifyunit == "degrees" {
+		x, y = kml.Simplify(c.simplifyby, x, y, c.simplify)
+	}
+	if c.globe {
+		globering(x, y, m, c)
+		return
+	}
+	switch c.shape {
+	case "line", "polyline":
+		for _, p := range kml.SplitLine(x, y, c.lon0) {
+			px, py := kml.MapCoords(p.X, p.Y, m)
+			draw(px, py, "path", m, c)
+		}
+	default:
+		for _, p := range kml.SplitPolygon(x, y, c.lon0) {
+			px, py := kml.MapCoords(p.X, p.Y, m)
+			draw(px, py, c.shape, m, c)
+		}
+	}
+}
+
+// label maps a label point to the canvas, on a globe if specified
+func label(x, y float64, m kml.Geometry, c config) ([]float64, []float64) {
+	if c.globe {
+		return kml.OrthoPoints([]float64{x}, []float64{y}, c.ortho, m)
+	}
+	return kml.MapCoords(kml.Recenter([]float64{x}, c.lon0), []float64{y}, m)
+}
+
+// globering makes markup for a polygon ring on a globe
+func globering(x, y []float64, m kml.Geometry, c config) {
+	switch c.shape {
+	case "fill", "polygon":
+		for _, p := range kml.OrthoPolygon(x, y, c.ortho, m) {
+			draw(p.X, p.Y, c.shape, m, c)
+		}
+	default:
+		for _, p := range kml.OrthoPolyline(x, y, c.ortho, m) {
+			draw(p.X, p.Y, "path", m, c)
+		}
+	}
+}
+
+// graticule draws meridians and parallels with degree labels, on a globe if specified
+func graticule(m kml.Geometry, c config) {
+	gr := kml.Grid{Interval: c.graticule, LineWidth: c.linewidth, Color: c.graticulecolor, TextSize: c.graticulesize, TextColor: c.textcolor}
+	if c.globe {
+		kml.OrthoGraticule(c.style, gr, c.ortho, m)
+		return
+	}
+	kml.DeckGraticule(c.style, gr, m)
+}
+
+// draw makes markup for mapped coordinates, simplified in canvas units if specified
+func draw(x, y []float64, shape string, m kml.Geometry, c config) {
+	if c.simplifyunit != "degrees" {
+		x, y = kml.Simplify(c.simplifyby, x, y, c.simplify)
+	}
+	kml.Deckshape(shape, c.style, x, y, c.linewidth, c.color, m)
+}
+
+// parseCenter reads the globe center in the form lat,long
+func parseCenter(s string) (kml.Ortho, error) {
+	var o kml.Ortho
+	f := strings.Split(s, ",")
+	if len(f) != 2 {
+		return o, fmt.Errorf("%q: center must be lat,long", s)
+	}
+	var err error
+	if o.Lat0, err = strconv.ParseFloat(strings.TrimSpace(f[0]), 64); err != nil {
+		return o, err
+	}
+	o.Long0, err = strconv.ParseFloat(strings.TrimSpace(f[1]), 64)
+	return o, err
+}
+
+// kmldump prints the coordinates of the outer boundaries of the polygons of every placemark
+func kmldump(pms []kml.Placemark) {
+	for _, pm := range pms {
+		for _, p := range pm.Polygons {
+			kml.DumpCoords(p.Outer.X, p.Outer.Y)
+		}
+	}
+}
+
+// readRules reads the styling rules file
+func readRules(c *config) error {
+	r, err := os.Open(c.rulefile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	c.rules, err = kml.ReadRules(r)
+	return err
+}
+
+// choropleth reads the data file, and assigns a color to each key by class
+func choropleth(c *config) error {
+	r, err := os.Open(c.choropleth)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	data, err := kml.ReadValues(r, c.key, c.value)
+	if err != nil {
+		return err
+	}
+	values := kml.Values(data)
+	var breaks []float64
+	if c.classify == "manual" {
+		b := []float64{}
+		for _, s := range strings.Fields(c.breaks) {
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return err
+			}
+			b = append(b, v)
+		}
+		breaks, err = classify.Manual(values, b)
+	} else {
+		breaks, err = classify.Classify(c.classify, values, c.classes)
+	}
+	if err != nil {
+		return err
+	}
+	colors, err := kml.Ramp(c.ramp, len(breaks)-1)
+	if err != nil {
+		return err
+	}
+	c.fills = kml.Choropleth(data, breaks, colors)
+	c.classbreaks, c.colors = breaks, colors
+	return nil
+}
+
+// legend makes the choropleth legend: class swatches or a gradient bar
+func legend(m kml.Geometry, c config) {
+	l := kml.Legend{Title: c.legendtitle, Corner: c.legend, Size: c.textsize, Color: c.textcolor}
+	switch c.legendtype {
+	case "gradient":
+		kml.GradientLegend(l, c.classbreaks[0], c.classbreaks[len(c.classbreaks)-1], c.colors, c.style, m)
+	default:
+		kml.ClassLegend(l, c.classbreaks, c.colors, c.style, m)
+	}
+}
+
+// stats prints the area, perimeter, bounding box and centroid of every selected placemark
+func stats(data []kml.Placemark, c config) {
+	fmt.Println("name\tarea_km2\tperimeter_km\tlongmin\tlongmax\tlatmin\tlatmax\tcentroid_long\tcentroid_lat")
+	for _, pm := range selected(data, c) {
+		var area, perimeter float64
+		for _, p := range pm.Polygons {
+			area += kml.Area(p)
+			perimeter += kml.Perimeter(p)
+		}
+		minx, maxx, miny, maxy := kml.PolygonBounds(pm.Polygons)
+		cx, cy := kml.Centroid(pm.Polygons)
+		fmt.Printf("%s\t%.2f\t%.2f\t%.5f\t%.5f\t%.5f\t%.5f\t%.5f\t%.5f\n",
+			pmname(pm, c.name), area/1e6, perimeter/1000, minx, maxx, miny, maxy, cx, cy)
+	}
+}
+
+// pmname returns the name of a placemark, preferring the value of its name attribute
+func pmname(pm kml.Placemark, attr string) string {
+	if name, ok := pm.Data[attr]; ok {
+		return name
+	}
+	return pm.Name
+}
+
+// begin begins a deck or decksh document
+func begin(style, color string) {
+	switch style {
+	case "deck":
+		kml.Deckbegin(color)
+	case "decksh":
+		kml.Deckshbegin(color)
+	}
+}
+
+// end ends a deck or decksh document
+func end(style string) {
+	switch style {
+	case "deck":
+		kml.Deckend()
+	case "decksh":
+		kml.Deckshend()
+	}
+}
+
+// Main reads the options and KML files named on the command line, and makes the map
+func Main(d Defaults) {
+
+	mapgeo := d.Geometry
+	cfg := config{name: d.Name}
+
+	// options
+	flag.Float64Var(&mapgeo.Xmin, "xmin", d.Geometry.Xmin, "canvas x minimum")
+	flag.Float64Var(&mapgeo.Xmax, "xmax", d.Geometry.Xmax, "canvas x maxmum")
+	flag.Float64Var(&mapgeo.Ymin, "ymin", d.Geometry.Ymin, "canvas y minimum")
+	flag.Float64Var(&mapgeo.Ymax, "ymax", d.Geometry.Ymax, "canvas y maximum")
+	flag.Float64Var(&mapgeo.Latmin, "latmin", d.Geometry.Latmin, "latitude x minimum")
+	flag.Float64Var(&mapgeo.Latmax, "latmax", d.Geometry.Latmax, "latitude x maxmum")
+	flag.Float64Var(&mapgeo.Longmin, "longmin", d.Geometry.Longmin, "longitude y minimum")
+	flag.Float64Var(&mapgeo.Longmax, "longmax", d.Geometry.Longmax, "longitude y maximum")
+	flag.Float64Var(&cfg.linewidth, "linewidth", 0.1, "line width")
+	flag.StringVar(&cfg.color, "color", "black", "line or fill color (name:op to specify opacity)")
+	flag.StringVar(&cfg.bbox, "bbox", "", "bounding box color (\"\" no box)")
+	flag.StringVar(&cfg.shape, "shape", "polyline", "polygon, polyline")
+	flag.StringVar(&cfg.style, "style", "deck", "deck, decksh, plain")
+	flag.StringVar(&cfg.bgcolor, "bgcolor", "", "background color")
+	flag.BoolVar(&cfg.fulldeck, "fulldeck", true, "make a full deck")
+	if d.Globe {
+		flag.BoolVar(&cfg.globe, "globe", false, "orthographic globe view")
+		flag.StringVar(&cfg.center, "center", "0,0", "globe center (lat,long)")
+	}
+	flag.StringVar(&cfg.fit, "fit", "stretch", "contain, cover, stretch")
+	flag.StringVar(&cfg.pagesize, "pagesize", "Letter", "page size (name or WxH), used by fit")
+	flag.Float64Var(&cfg.pad, "pad", 0, "padding around the fitted map (percent)")
+	flag.Float64Var(&cfg.lon0, "lon0", 0, "center longitude (longmin and longmax are relative to it)")
+	flag.Float64Var(&cfg.simplify, "simplify", 0, "simplification tolerance (0 for none)")
+	flag.StringVar(&cfg.simplifyby, "simplifyby", "dp", "simplification method: dp (Douglas-Peucker), vw (Visvalingam-Whyatt)")
+	flag.StringVar(&cfg.simplifyunit, "simplifyunit", "canvas", "simplification tolerance units: canvas, degrees")
+	flag.BoolVar(&cfg.topology, "topology", false, "simplify shared borders once, keeping neighbors aligned")
+	flag.BoolVar(&cfg.stats, "stats", false, "only report area, perimeter, bounding box and centroid of each placemark")
+	flag.StringVar(&cfg.text, "text", "", "label placemarks (c, b, e alignment; \"\" for no labels)")
+	flag.Float64Var(&cfg.textsize, "textsize", 0.5, "textsize")
+	flag.StringVar(&cfg.textcolor, "textcolor", "black", "textcolor")
+	flag.BoolVar(&cfg.declutter, "declutter", false, "place labels so they don't overlap")
+	flag.BoolVar(&cfg.leaders, "leaders", false, "use leader lines for labels that don't fit (with declutter)")
+	flag.StringVar(&cfg.rankby, "rankby", "", "attribute to rank labels by (default area)")
+	flag.StringVar(&cfg.choropleth, "choropleth", "", "CSV file of values to color placemarks by")
+	flag.StringVar(&cfg.key, "key", d.Key, "attribute joining placemarks to the key column of the choropleth data")
+	flag.StringVar(&cfg.value, "value", "value", "choropleth data value column")
+	flag.IntVar(&cfg.classes, "classes", 5, "number of choropleth classes")
+	flag.StringVar(&cfg.classify, "classify", "quantile", "classification: equal, quantile, stddev, jenks, manual")
+	flag.StringVar(&cfg.breaks, "breaks", "", "class boundaries for manual classification (space separated)")
+	flag.StringVar(&cfg.ramp, "ramp", "#eff3ff #08519c", "choropleth colors (palette name, or two colors: from to)")
+	flag.StringVar(&cfg.legend, "legend", "", "choropleth legend corner: ul, ur, ll, lr (\"\" for no legend)")
+	flag.StringVar(&cfg.legendtitle, "legendtitle", "", "legend title")
+	flag.StringVar(&cfg.legendtype, "legendtype", "classes", "legend type: classes, gradient")
+	flag.Float64Var(&cfg.graticule, "graticule", 0, "draw meridians and parallels every so many degrees (0 for none)")
+	flag.StringVar(&cfg.graticulecolor, "graticulecolor", "gray:50", "graticule color")
+	flag.Float64Var(&cfg.graticulesize, "graticulesize", 1, "graticule label size (0 for no labels)")
+	flag.StringVar(&cfg.dots, "dots", "", "attribute to make a dot-density layer from (\"\" for none)")
+	flag.Float64Var(&cfg.dotvalue, "dotvalue", 1, "attribute value represented by each dot")
+	flag.Float64Var(&cfg.dotsize, "dotsize", 0.1, "dot size")
+	flag.StringVar(&cfg.dotcolor, "dotcolor", "black", "dot color")
+	flag.Int64Var(&cfg.seed, "seed", 1, "random seed for dot placement")
+	flag.StringVar(&cfg.where, "where", "", "only use placemarks matching an expression, like 'continent == \"Africa\"'")
+	flag.StringVar(&cfg.rulefile, "rules", "", "JSON file of styling rules")
+	flag.StringVar(&cfg.dissolve, "dissolve", "", "merge placemarks with the same value of an attribute, removing the borders between them")
+	flag.Parse()
+
+	mapgeo.Longmin += cfg.lon0
+	mapgeo.Longmax += cfg.lon0
+
+	pw, ph, err := kml.PageSize(cfg.pagesize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	mapgeo = kml.Fit(mapgeo, cfg.fit, pw, ph, cfg.pad)
+
+	if cfg.globe {
+		cfg.ortho, err = parseCenter(cfg.center)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+	// select placemarks, if specified
+	cfg.match = func(kml.Placemark) bool { return true }
+	if len(cfg.where) > 0 {
+		cfg.match, err = kml.Where(cfg.where)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+	// read styling rules, if specified
+	if len(cfg.rulefile) > 0 {
+		if err := readRules(&cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+	if len(cfg.choropleth) > 0 {
+		if err := choropleth(&cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+	// don't do any generation if stats only
+	if cfg.stats {
+		cfg.fulldeck = false
+	}
+	// add deck/slide markup, if specified
+	if cfg.fulldeck {
+		begin(cfg.style, cfg.bgcolor)
+	}
+	for _, filename := range flag.Args() {
+		// read data
+		data, err := readData(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			continue
+		}
+		if cfg.stats {
+			stats(data, cfg)
+			continue
+		}
+		// make a bounding box (or the globe disc), if specified
+		if len(cfg.bbox) > 0 {
+			if cfg.globe {
+				kml.Globe(mapgeo, cfg.bbox, cfg.style)
+			} else {
+				kml.BoundingBox(mapgeo, cfg.bbox, cfg.style)
+			}
+		}
+		// draw the graticule, if specified
+		if cfg.graticule > 0 {
+			graticule(mapgeo, cfg)
+		}
+		switch cfg.style {
+		case "deck", "decksh":
+			kmldeck(data, mapgeo, cfg)
+		case "plain", "dump":
+			kmldump(data)
+		}
+	}
+	// make the legend, if specified
+	if len(cfg.legend) > 0 && len(cfg.classbreaks) > 1 && !cfg.stats {
+		legend(mapgeo, cfg)
+	}
+	// end the deck, if specified
+	if cfg.fulldeck {
+		end(cfg.style)
+	}
+}
@@ -8,14 +8,16 @@ import (
 )
 
 const (
-	linefmt    = "<line xp1=\"%.5f\" yp1=\"%.5f\" xp2=\"%.5f\" yp2=\"%.5f\" sp=\"%.5f\" color=\"%s\" opacity=\"%s\"/>\n"
-	dotfmt     = "<ellipse xp=\"%.3f\" yp=\"%.3f\" wp=\"%.3f\" hr=\"100\" color=\"%s\" opacity=\"%s\"/>\n"
-	textfmt    = "<text align=\"c\" sp=\"1.0\" xp=\"%.5f\" yp=\"%.5f\">(%.5f, %.5f)</text>\n"
-	rectfmt    = "<rect xp=\"%.5f\" yp=\"%.5f\" wp=\"%.5f\" hp=\"%.5f\" color=\"%s\" opacity=\"10\"/>\n"
-	dshlinefmt = "line %.5f %.5f %.5f %.5f %.2f \"%s\" %s\n"
-	dshdotfmt  = "circle %.3f %.3f %.3f \"%s\" %s\n"
-	dshtextfmt = "ctext \"(%.5f, %.5f)\" %.5f %.5f 1.0\n"
-	dshrectfmt = "rect %.5f %.5f %.5f %.5f \"%s\" 10\n"
+	linefmt     = "<line xp1=\"%.5f\" yp1=\"%.5f\" xp2=\"%.5f\" yp2=\"%.5f\" sp=\"%.5f\" color=\"%s\" opacity=\"%s\"/>\n"
+	dotfmt      = "<ellipse xp=\"%.3f\" yp=\"%.3f\" wp=\"%.3f\" hr=\"100\" color=\"%s\" opacity=\"%s\"/>\n"
+	textfmt     = "<text align=\"c\" sp=\"1.0\" xp=\"%.5f\" yp=\"%.5f\">(%.5f, %.5f)</text>\n"
+	rectfmt     = "<rect xp=\"%.5f\" yp=\"%.5f\" wp=\"%.5f\" hp=\"%.5f\" color=\"%s\" opacity=\"10\"/>\n"
+	dshlinefmt  = "line %.5f %.5f %.5f %.5f %.2f \"%s\" %s\n"
+	dshdotfmt   = "circle %.3f %.3f %.3f \"%s\" %s\n"
+	dshtextfmt  = "ctext \"(%.5f, %.5f)\" %.5f %.5f 1.0\n"
+	dshrectfmt  = "rect %.5f %.5f %.5f %.5f \"%s\" 10\n"
+	labelfmt    = "<text align=\"%s\" xp=\"%.3f\" yp=\"%.3f\" sp=\"%.3f\" color=\"%s\" opacity=\"%s\">%s</text>\n"
+	dshlabelfmt = "%s \"%s\" %.3f %.3f %.3f \"sans\" \"%s\" \"%s\"\n"
 )
 
 // geometry defines the canvas and map boundaries
@@ -28,8 +30,15 @@ type Geometry struct {
 
 // locdata
 type Locdata struct {
+	X, Y   []float64
+	Name   []string
+	Rank   []float64
+	Fields [][]string // all fields of each row
+}
+
+// Path is a sequence of x, y coordinates
+type Path struct {
 	X, Y []float64
-	Name []string
 }
 
 // xmlmap defines the XML substitutions
@@ -99,6 +108,20 @@ func mapData(x, y float64, g Geometry) (float64, float64) {
 	return x, y
 }
 
+// MapCoords maps raw long/lat coordinates to canvas coordinates, making new slices
+func MapCoords(x, y []float64, g Geometry) ([]float64, []float64) {
+	n := len(x)
+	if n != len(y) {
+		return nil, nil
+	}
+	xp := make([]float64, n)
+	yp := make([]float64, n)
+	for i := 0; i < n; i++ {
+		xp[i], yp[i] = mapData(x[i], y[i], g)
+	}
+	return xp, yp
+}
+
 // filter makes new coordinates contained within the boundary defined by g.
 func filter(x, y []float64, g Geometry) ([]float64, []float64) {
 	nc := len(x)
@@ -139,8 +162,9 @@ func DeckPoint(x, y []float64, color string, lw float64) {
 	}
 }
 
-// Deckpolygon makes deck markup for a polygon given x, y coordinates slices
+// Deckpolygon makes deck markup for a polygon given x, y coordinates slices, clipped to the canvas
 func Deckpolygon(x, y []float64, color string, g Geometry) {
+	x, y = ClipPolygon(x, y, g)
 	nc := len(x)
 	if nc < 3 || nc != len(y) {
 		return
@@ -184,8 +208,9 @@ func DeckshPoint(x, y []float64, color string, lw float64) {
 	}
 }
 
-// Deckshpoly makes decksh markup for a polygon or polyline given x, y slices
+// Deckshpolygon makes decksh markup for a polygon given x, y slices, clipped to the canvas
 func Deckshpolygon(x, y []float64, color string, g Geometry) {
+	x, y = ClipPolygon(x, y, g)
 	nc := len(x)
 	if nc < 3 || nc != len(y) {
 		return
@@ -218,16 +243,44 @@ func Deckshpolyline(x, y []float64, lw float64, color string, g Geometry) {
 	deckshline(x[0], y[0], x[lx-1], y[lx-1], lw, fill, op, g)
 }
 
-// deckline makes a line in deck markup
+// Deckpath makes deck markup for an open path given x, y coordinate slices
+func Deckpath(x, y []float64, lw float64, color string, g Geometry) {
+	lx := len(x)
+	if lx < 2 || lx != len(y) {
+		return
+	}
+	fill, op := colorop(color)
+	for _, p := range ClipPath(x, y, g) {
+		for i := 0; i < len(p.X)-1; i++ {
+			fmt.Printf(linefmt, p.X[i], p.Y[i], p.X[i+1], p.Y[i+1], lw, fill, op)
+		}
+	}
+}
+
+// Deckshpath makes decksh markup for an open path given x, y coordinate slices
+func Deckshpath(x, y []float64, lw float64, color string, g Geometry) {
+	lx := len(x)
+	if lx < 2 || lx != len(y) {
+		return
+	}
+	fill, op := colorop(color)
+	for _, p := range ClipPath(x, y, g) {
+		for i := 0; i < len(p.X)-1; i++ {
+			fmt.Printf(dshlinefmt, p.X[i], p.Y[i], p.X[i+1], p.Y[i+1], lw, fill, op)
+		}
+	}
+}
+
+// deckline makes a line in deck markup, clipped to the canvas
 func deckline(x1, y1, x2, y2, lw float64, fill, op string, g Geometry) {
-	if x1 >= g.Xmin && x2 <= g.Xmax && y1 >= g.Ymin && y2 <= g.Ymax {
+	if x1, y1, x2, y2, ok := ClipLine(x1, y1, x2, y2, g); ok {
 		fmt.Printf(linefmt, x1, y1, x2, y2, lw, fill, op)
 	}
 }
 
-// deckshline makes a line in decksh markup
+// deckshline makes a line in decksh markup, clipped to the canvas
 func deckshline(x1, y1, x2, y2, lw float64, fill, op string, g Geometry) {
-	if x1 >= g.Xmin && x2 <= g.Xmax && y1 >= g.Ymin && y2 <= g.Ymax {
+	if x1, y1, x2, y2, ok := ClipLine(x1, y1, x2, y2, g); ok {
 		fmt.Printf(dshlinefmt, x1, y1, x2, y2, lw, fill, op)
 	}
 }
@@ -239,6 +292,8 @@ func Deckshape(shape, style string, x, y []float64, shapesize float64, color str
 		switch shape {
 		case "line", "polyline":
 			Deckpolyline(x, y, shapesize, color, g)
+		case "path":
+			Deckpath(x, y, shapesize, color, g)
 		case "fill", "polygon":
 			Deckpolygon(x, y, color, g)
 		case "dot", "circle":
@@ -248,6 +303,8 @@ func Deckshape(shape, style string, x, y []float64, shapesize float64, color str
 		switch shape {
 		case "line", "polyline":
 			Deckshpolyline(x, y, shapesize, color, g)
+		case "path":
+			Deckshpath(x, y, shapesize, color, g)
 		case "fill", "polygon":
 			Deckshpolygon(x, y, color, g)
 		case "dot", "circle":
@@ -258,7 +315,8 @@ func Deckshape(shape, style string, x, y []float64, shapesize float64, color str
 	}
 }
 
-func textadj(align string, size float64) (float64, float64) {
+// textadj returns the alignment and position adjustment for text labels
+func textadj(align string, size float64) (string, float64, float64) {
 	var xdiff, ydiff float64
 	switch align {
 	case "c", "ctext":
@@ -276,23 +334,34 @@ func textadj(align string, size float64) (float64, float64) {
 		align = "c"
 		ydiff = size / 2
 	}
-	return xdiff, ydiff
+	return align, xdiff, ydiff
+}
+
+// dshalign maps text alignment to the decksh text command
+func dshalign(align string) string {
+	switch align {
+	case "l":
+		return "text"
+	case "e":
+		return "etext"
+	default:
+		return "ctext"
+	}
 }
 
 func deckText(align string, x, y []float64, names []string, size float64, color string) {
-	xdiff, ydiff := textadj(align, size)
+	align, xdiff, ydiff := textadj(align, size)
 	fill, op := colorop(color)
 	for i := 0; i < len(x); i++ {
-		fmt.Printf("<text align=\"%s\" xp=\"%.3f\" yp=\"%.3f\" sp=\"%.3f\" color=\"%s\" opacity=\"%s\">%s</text>\n",
-			align, x[i]+xdiff, y[i]+ydiff, size, fill, op, xmlesc(names[i]))
+		fmt.Printf(labelfmt, align, x[i]+xdiff, y[i]+ydiff, size, fill, op, xmlesc(names[i]))
 	}
 }
 
 func deckshText(align string, x, y []float64, names []string, size float64, color string) {
-	xdiff, ydiff := textadj(align, size)
+	align, xdiff, ydiff := textadj(align, size)
 	fill, op := colorop(color)
 	for i := 0; i < len(x); i++ {
-		fmt.Printf("%s \"%s\" %.3f %.3f %.3f \"sans\" \"%s\" \"%s\"\n", align, names[i], x[i]+xdiff, y[i]+ydiff, size, fill, op)
+		fmt.Printf(dshlabelfmt, dshalign(align), names[i], x[i]+xdiff, y[i]+ydiff, size, fill, op)
 	}
 }
 
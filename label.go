@@ -0,0 +1,130 @@
+package kml
+
+import (
+	"container/heap"
+	"math"
+)
+
+// inring reports whether a point is inside a ring (even-odd rule)
+func inring(px, py float64, x, y []float64) bool {
+	in := false
+	n := len(x)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		if (y[i] > py) != (y[j] > py) && px < (x[j]-x[i])*(py-y[i])/(y[j]-y[i])+x[i] {
+			in = !in
+		}
+	}
+	return in
+}
+
+// contains reports whether a point is inside a polygon, and not in one of its holes
+func contains(p Polygon, px, py float64) bool {
+	if !inring(px, py, p.Outer.X, p.Outer.Y) {
+		return false
+	}
+	for _, h := range p.Inner {
+		if inring(px, py, h.X, h.Y) {
+			return false
+		}
+	}
+	return true
+}
+
+// edgedist returns the distance from a point to the nearest edge of a polygon,
+// negative if the point is outside
+func edgedist(p Polygon, px, py float64) float64 {
+	d := math.Inf(1)
+	ring := func(r Path) {
+		n := len(r.X)
+		for i, j := 0, n-1; i < n; j, i = i, i+1 {
+			d = math.Min(d, segdist(px, py, r.X[j], r.Y[j], r.X[i], r.Y[i]))
+		}
+	}
+	ring(p.Outer)
+	for _, h := range p.Inner {
+		ring(h)
+	}
+	if !contains(p, px, py) {
+		return -d
+	}
+	return d
+}
+
+// cell is a square in the polylabel search
+type cell struct {
+	x, y, h, d, max float64
+}
+
+func newcell(x, y, h float64, p Polygon) *cell {
+	d := edgedist(p, x, y)
+	return &cell{x: x, y: y, h: h, d: d, max: d + h*math.Sqrt2}
+}
+
+// cellqueue orders cells by their potential distance, greatest first
+type cellqueue []*cell
+
+func (q cellqueue) Len() int            { return len(q) }
+func (q cellqueue) Less(i, j int) bool  { return q[i].max > q[j].max }
+func (q cellqueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *cellqueue) Push(v interface{}) { *q = append(*q, v.(*cell)) }
+func (q *cellqueue) Pop() interface{} {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+// Polylabel returns the pole of inaccessibility of a polygon: the interior point
+// farthest from its edges (and holes), along with that distance. The point is found
+// to within precision, in the units of the coordinates.
+func Polylabel(p Polygon, precision float64) (float64, float64, float64) {
+	minx, maxx, miny, maxy := Bounds(p.Outer.X, p.Outer.Y)
+	w, h := maxx-minx, maxy-miny
+	size := math.Min(w, h)
+	if size == 0 || precision <= 0 {
+		return minx, miny, 0
+	}
+	q := cellqueue{}
+	for x := minx; x < maxx; x += size {
+		for y := miny; y < maxy; y += size {
+			heap.Push(&q, newcell(x+size/2, y+size/2, size/2, p))
+		}
+	}
+	// start with the centroid, or the center of the bounding box
+	cx, cy := ringCentroid(p.Outer.X, p.Outer.Y)
+	best := newcell(cx, cy, 0, p)
+	if b := newcell(minx+w/2, miny+h/2, 0, p); b.d > best.d {
+		best = b
+	}
+	for q.Len() > 0 {
+		c := heap.Pop(&q).(*cell)
+		if c.d > best.d {
+			best = c
+		}
+		if c.max-best.d <= precision {
+			continue
+		}
+		s := c.h / 2
+		heap.Push(&q, newcell(c.x-s, c.y-s, s, p))
+		heap.Push(&q, newcell(c.x+s, c.y-s, s, p))
+		heap.Push(&q, newcell(c.x-s, c.y+s, s, p))
+		heap.Push(&q, newcell(c.x+s, c.y+s, s, p))
+	}
+	return best.x, best.y, best.d
+}
+
+// LabelPoint returns a point for labeling a set of polygons: the pole of
+// inaccessibility of the polygon with the largest area
+func LabelPoint(ps []Polygon, precision float64) (float64, float64) {
+	best, area := -1, 0.0
+	for i, p := range ps {
+		if a := Area(p); best < 0 || a > area {
+			best, area = i, a
+		}
+	}
+	if best < 0 {
+		return 0, 0
+	}
+	x, y, _ := Polylabel(ps[best], precision)
+	return x, y
+}
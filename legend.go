@@ -0,0 +1,176 @@
+package kml
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	swatchfmt    = "<rect xp=\"%.3f\" yp=\"%.3f\" wp=\"%.3f\" hp=\"%.3f\" color=\"%s\" opacity=\"%s\"/>\n"
+	dshswatchfmt = "rect %.3f %.3f %.3f %.3f \"%s\" %s\n"
+	gradientstep = 50 // slices in a gradient bar
+)
+
+// Legend describes the placement and text of a map legend
+type Legend struct {
+	Title  string
+	Corner string  // ul, ur, ll, lr (upper/lower, left/right)
+	Size   float64 // text size; swatches and spacing are scaled from it
+	Color  string  // text color
+}
+
+// LegendNumber formats a value for a legend, abbreviating thousands (k) and millions (M)
+func LegendNumber(v float64) string {
+	a := math.Abs(v)
+	switch {
+	case a >= 1e9:
+		return trimzero(strconv.FormatFloat(v/1e9, 'f', 1, 64)) + "B"
+	case a >= 1e6:
+		return trimzero(strconv.FormatFloat(v/1e6, 'f', 1, 64)) + "M"
+	case a >= 1e4:
+		return trimzero(strconv.FormatFloat(v/1e3, 'f', 1, 64)) + "k"
+	default:
+		return strconv.FormatFloat(v, 'g', 4, 64)
+	}
+}
+
+// trimzero removes a trailing .0
+func trimzero(s string) string {
+	return strings.TrimSuffix(s, ".0")
+}
+
+// origin returns the upper left of a legend of width w and height h, in its corner of the canvas
+func (l Legend) origin(w, h float64, g Geometry) (float64, float64) {
+	pad := l.Size
+	x, y := g.Xmin+pad, g.Ymax-pad
+	if strings.HasSuffix(l.Corner, "r") {
+		x = g.Xmax - pad - w
+	}
+	if strings.HasPrefix(l.Corner, "l") {
+		y = g.Ymin + pad + h
+	}
+	return x, y
+}
+
+// text makes a left-aligned legend label with its baseline at x, y
+func (l Legend) text(style, s string, x, y float64) {
+	fill, op := colorop(l.Color)
+	switch style {
+	case "deck":
+		fmt.Printf(labelfmt, "l", x, y, l.Size, fill, op, xmlesc(s))
+	case "decksh":
+		fmt.Printf(dshlabelfmt, "text", s, x, y, l.Size, fill, op)
+	}
+}
+
+// swatch makes a filled rectangle, given its upper left corner
+func swatch(style string, x, y, w, h float64, color string) {
+	fill, op := colorop(color)
+	switch style {
+	case "deck":
+		fmt.Printf(swatchfmt, x+w/2, y-h/2, w, h, fill, op)
+	case "decksh":
+		fmt.Printf(dshswatchfmt, x+w/2, y-h/2, w, h, fill, op)
+	}
+}
+
+// ClassLegend makes a legend of class swatches, each labeled with its range,
+// given class boundaries (n+1 values) and a color for each class
+func ClassLegend(l Legend, breaks []float64, colors []string, style string, g Geometry) {
+	n := len(breaks) - 1
+	if n < 1 || len(colors) < n {
+		return
+	}
+	row := l.Size * 2
+	sw, sh := l.Size*2, l.Size*1.5
+	labels := make([]string, n)
+	w := textwidth(l.Title, l.Size)
+	for i := 0; i < n; i++ {
+		labels[i] = LegendNumber(breaks[i]) + " – " + LegendNumber(breaks[i+1])
+		w = math.Max(w, sw+l.Size+textwidth(labels[i], l.Size))
+	}
+	h := float64(n) * row
+	if len(l.Title) > 0 {
+		h += row
+	}
+	x, y := l.origin(w, h, g)
+	if len(l.Title) > 0 {
+		l.text(style, l.Title, x, y-l.Size)
+		y -= row
+	}
+	for i := 0; i < n; i++ {
+		swatch(style, x, y, sw, sh, colors[i])
+		l.text(style, labels[i], x+sw+l.Size, y-sh/2-l.Size/3)
+		y -= row
+	}
+}
+
+// GradientLegend makes a continuous color bar from min to max, labeled at the ends
+func GradientLegend(l Legend, min, max float64, colors []string, style string, g Geometry) {
+	if len(colors) == 0 {
+		return
+	}
+	row := l.Size * 2
+	bw := l.Size * 15
+	w := math.Max(bw, textwidth(l.Title, l.Size))
+	h := row * 2
+	if len(l.Title) > 0 {
+		h += row
+	}
+	x, y := l.origin(w, h, g)
+	if len(l.Title) > 0 {
+		l.text(style, l.Title, x, y-l.Size)
+		y -= row
+	}
+	step := bw / gradientstep
+	for i := 0; i < gradientstep; i++ {
+		t := (float64(i) + 0.5) / gradientstep
+		swatch(style, x+float64(i)*step, y, step*1.05, l.Size*1.5, interpolate(colors, t))
+	}
+	y -= row + l.Size
+	maxlabel := LegendNumber(max)
+	l.text(style, LegendNumber(min), x, y)
+	l.text(style, maxlabel, x+bw-textwidth(maxlabel, l.Size), y)
+}
+
+// CircleLegend makes a graduated-circle legend: a circle for each value, labeled with the value
+// (largest first), with the given canvas diameters (from SymbolSizes, on the same scale as the map's symbols)
+func CircleLegend(l Legend, values, sizes []float64, color, style string, g Geometry) {
+	n := len(values)
+	if n == 0 || len(sizes) < n {
+		return
+	}
+	dmax := 0.0
+	w := textwidth(l.Title, l.Size)
+	h := 0.0
+	for i := 0; i < n; i++ {
+		dmax = math.Max(dmax, sizes[i])
+		h += math.Max(sizes[i], l.Size) + l.Size
+	}
+	for i := 0; i < n; i++ {
+		w = math.Max(w, dmax+l.Size+textwidth(LegendNumber(values[i]), l.Size))
+	}
+	if len(l.Title) > 0 {
+		h += l.Size * 2
+	}
+	x, y := l.origin(w, h, g)
+	if len(l.Title) > 0 {
+		l.text(style, l.Title, x, y-l.Size)
+		y -= l.Size * 2
+	}
+	fill, op := colorop(color)
+	for i := n - 1; i >= 0; i-- {
+		rh := math.Max(sizes[i], l.Size)
+		cx, cy := x+dmax/2, y-rh/2
+		switch style {
+		case "deck":
+			fmt.Printf(dotfmt, cx, cy, sizes[i], fill, op)
+		case "decksh":
+			fmt.Printf(dshdotfmt, cx, cy, sizes[i], fill, op)
+		}
+		l.text(style, LegendNumber(values[i]), x+dmax+l.Size, cy-l.Size/3)
+		y -= rh + l.Size
+	}
+}
@@ -0,0 +1,186 @@
+package kml
+
+import "math"
+
+const (
+	// EarthRadius is the mean radius of the earth in meters
+	EarthRadius = 6371008.8
+	wgs84a      = 6378137.0         // WGS84 semi-major axis (meters)
+	wgs84f      = 1 / 298.257223563 // WGS84 flattening
+)
+
+// Haversine returns the great circle distance in meters between two long/lat points
+func Haversine(long1, lat1, long2, lat2 float64) float64 {
+	p1, p2 := lat1*deg2rad, lat2*deg2rad
+	dp := p2 - p1
+	dl := (long2 - long1) * deg2rad
+	a := math.Sin(dp/2)*math.Sin(dp/2) + math.Cos(p1)*math.Cos(p2)*math.Sin(dl/2)*math.Sin(dl/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Vincenty returns the distance in meters between two long/lat points on the WGS84 ellipsoid.
+// For nearly antipodal points, where the method does not converge, the haversine distance is returned.
+func Vincenty(long1, lat1, long2, lat2 float64) float64 {
+	b := wgs84a * (1 - wgs84f)
+	L := (long2 - long1) * deg2rad
+	U1 := math.Atan((1 - wgs84f) * math.Tan(lat1*deg2rad))
+	U2 := math.Atan((1 - wgs84f) * math.Tan(lat2*deg2rad))
+	sinU1, cosU1 := math.Sincos(U1)
+	sinU2, cosU2 := math.Sincos(U2)
+
+	lambda := L
+	var sinSigma, cosSigma, sigma, cos2Alpha, cos2SigmaM float64
+	for i := 0; i < 200; i++ {
+		sinLambda, cosLambda := math.Sincos(lambda)
+		sinSigma = math.Hypot(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+		if sinSigma == 0 {
+			return 0 // coincident points
+		}
+		cosSigma = sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cos2Alpha = 1 - sinAlpha*sinAlpha
+		cos2SigmaM = 0
+		if cos2Alpha != 0 {
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cos2Alpha
+		}
+		C := wgs84f / 16 * cos2Alpha * (4 + wgs84f*(4-3*cos2Alpha))
+		prev := lambda
+		lambda = L + (1-C)*wgs84f*sinAlpha*(sigma+C*sinSigma*(cos2SigmaM+C*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-prev) < 1e-12 {
+			u2 := cos2Alpha * (wgs84a*wgs84a - b*b) / (b * b)
+			A := 1 + u2/16384*(4096+u2*(-768+u2*(320-175*u2)))
+			B := u2 / 1024 * (256 + u2*(-128+u2*(74-47*u2)))
+			ds := B * sinSigma * (cos2SigmaM + B/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+				B/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+			return b * A * (sigma - ds)
+		}
+	}
+	return Haversine(long1, lat1, long2, lat2)
+}
+
+// Length returns the length in meters of a long/lat line,
+// measured with the named method: "haversine" (the default) or "vincenty"
+func Length(method string, x, y []float64) float64 {
+	dist := Haversine
+	if method == "vincenty" {
+		dist = Vincenty
+	}
+	var d float64
+	for i := 1; i < len(x) && i < len(y); i++ {
+		d += dist(x[i-1], y[i-1], x[i], y[i])
+	}
+	return d
+}
+
+// RingArea returns the signed geodesic area in square meters of a long/lat ring
+// (positive when counterclockwise), using the spherical excess of each edge
+func RingArea(x, y []float64) float64 {
+	n := len(x)
+	if n < 3 || n != len(y) {
+		return 0
+	}
+	var a float64
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		dl := math.Remainder(x[j]-x[i], 360) * deg2rad
+		a += dl * (2 + math.Sin(y[i]*deg2rad) + math.Sin(y[j]*deg2rad))
+	}
+	return -a * EarthRadius * EarthRadius / 2
+}
+
+// Area returns the geodesic area in square meters of a polygon, less its holes
+func Area(p Polygon) float64 {
+	a := math.Abs(RingArea(p.Outer.X, p.Outer.Y))
+	for _, h := range p.Inner {
+		a -= math.Abs(RingArea(h.X, h.Y))
+	}
+	return a
+}
+
+// Perimeter returns the length in meters of the boundary of a polygon, including its holes
+func Perimeter(p Polygon) float64 {
+	d := Length("haversine", closed(p.Outer.X), closed(p.Outer.Y))
+	for _, h := range p.Inner {
+		d += Length("haversine", closed(h.X), closed(h.Y))
+	}
+	return d
+}
+
+// closed returns a ring that ends where it starts
+func closed(v []float64) []float64 {
+	n := len(v)
+	if n == 0 || v[0] == v[n-1] {
+		return v
+	}
+	return append(v[:n:n], v[0])
+}
+
+// Bounds returns the minimum and maximum of x and y
+func Bounds(x, y []float64) (float64, float64, float64, float64) {
+	if len(x) == 0 || len(y) == 0 {
+		return 0, 0, 0, 0
+	}
+	minx, maxx, miny, maxy := x[0], x[0], y[0], y[0]
+	for i := 1; i < len(x) && i < len(y); i++ {
+		minx = math.Min(minx, x[i])
+		maxx = math.Max(maxx, x[i])
+		miny = math.Min(miny, y[i])
+		maxy = math.Max(maxy, y[i])
+	}
+	return minx, maxx, miny, maxy
+}
+
+// PolygonBounds returns the long/lat bounds of a set of polygons
+func PolygonBounds(ps []Polygon) (float64, float64, float64, float64) {
+	x := []float64{}
+	y := []float64{}
+	for _, p := range ps {
+		x = append(x, p.Outer.X...)
+		y = append(y, p.Outer.Y...)
+	}
+	return Bounds(x, y)
+}
+
+// ringCentroid returns the planar centroid of a ring
+func ringCentroid(x, y []float64) (float64, float64) {
+	n := len(x)
+	var a, cx, cy float64
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		f := x[i]*y[j] - x[j]*y[i]
+		a += f
+		cx += (x[i] + x[j]) * f
+		cy += (y[i] + y[j]) * f
+	}
+	if a == 0 {
+		return mean(x), mean(y)
+	}
+	return cx / (3 * a), cy / (3 * a)
+}
+
+// Centroid returns the area-weighted centroid (long, lat) of a set of polygons with holes
+func Centroid(ps []Polygon) (float64, float64) {
+	var sx, sy, sa float64
+	add := func(r Path, sign float64) {
+		if len(r.X) < 3 {
+			return
+		}
+		a := sign * math.Abs(RingArea(r.X, r.Y))
+		cx, cy := ringCentroid(r.X, r.Y)
+		sx += cx * a
+		sy += cy * a
+		sa += a
+	}
+	for _, p := range ps {
+		add(p.Outer, 1)
+		for _, h := range p.Inner {
+			add(h, -1)
+		}
+	}
+	if sa == 0 {
+		minx, maxx, miny, maxy := PolygonBounds(ps)
+		return (minx + maxx) / 2, (miny + maxy) / 2
+	}
+	return sx / sa, sy / sa
+}
@@ -0,0 +1,280 @@
+package kml
+
+import "math"
+
+const (
+	deg2rad  = math.Pi / 180
+	rad2deg  = 180 / math.Pi
+	arcsteps = 72 // horizon arc segments in a full circle
+)
+
+// Ortho defines an orthographic (globe) view centered at Long0, Lat0
+type Ortho struct {
+	Long0, Lat0 float64
+}
+
+// vec3 is a point on the unit sphere
+type vec3 struct {
+	x, y, z float64
+}
+
+// sphere converts long/lat in degrees to a point on the unit sphere
+func sphere(long, lat float64) vec3 {
+	lo, la := long*deg2rad, lat*deg2rad
+	return vec3{math.Cos(la) * math.Cos(lo), math.Cos(la) * math.Sin(lo), math.Sin(la)}
+}
+
+// dot returns the dot product of two vectors
+func (a vec3) dot(b vec3) float64 {
+	return a.x*b.x + a.y*b.y + a.z*b.z
+}
+
+// lonlat converts a (not necessarily unit) vector to long/lat in degrees
+func (a vec3) lonlat() (float64, float64) {
+	return math.Atan2(a.y, a.x) * rad2deg, math.Atan2(a.z, math.Hypot(a.x, a.y)) * rad2deg
+}
+
+// project maps long/lat to the unit disc, returning the cosine of the distance from the center;
+// points with a negative cosine are on the far hemisphere.
+func (o Ortho) project(long, lat float64) (float64, float64, float64) {
+	dl := (long - o.Long0) * deg2rad
+	la, la0 := lat*deg2rad, o.Lat0*deg2rad
+	x := math.Cos(la) * math.Sin(dl)
+	y := math.Cos(la0)*math.Sin(la) - math.Sin(la0)*math.Cos(la)*math.Cos(dl)
+	c := math.Sin(la0)*math.Sin(la) + math.Cos(la0)*math.Cos(la)*math.Cos(dl)
+	return x, y, c
+}
+
+// horizon finds where the great circle between two points crosses the horizon,
+// returning the angle of the crossing on the unit disc
+func (o Ortho) horizon(x1, y1, x2, y2 float64) float64 {
+	c := sphere(o.Long0, o.Lat0)
+	a, b := sphere(x1, y1), sphere(x2, y2)
+	da, db := a.dot(c), b.dot(c)
+	t := da / (da - db)
+	p := vec3{a.x + t*(b.x-a.x), a.y + t*(b.y-a.y), a.z + t*(b.z-a.z)}
+	hx, hy, _ := o.project(p.lonlat())
+	return math.Atan2(hy, hx)
+}
+
+// disc maps a point on the unit disc to the canvas, keeping the globe round
+func disc(x, y float64, g Geometry) (float64, float64) {
+	cx := g.Xmin + (g.Xmax-g.Xmin)/2
+	cy := g.Ymin + (g.Ymax-g.Ymin)/2
+	r := math.Min(g.Xmax-g.Xmin, g.Ymax-g.Ymin) / 2
+	return cx + x*r, cy + y*r
+}
+
+// arc appends points along the horizon from angle a1 to a2, going counterclockwise if ccw is true
+// (or clockwise), so that the interior of a ring with that orientation stays on its left
+func arc(x, y []float64, a1, a2 float64, ccw bool, g Geometry) ([]float64, []float64) {
+	d := math.Mod(a2-a1, 2*math.Pi)
+	switch {
+	case ccw && d < 0:
+		d += 2 * math.Pi
+	case !ccw && d > 0:
+		d -= 2 * math.Pi
+	}
+	n := int(math.Ceil(math.Abs(d) / (2 * math.Pi / arcsteps)))
+	for i := 1; i < n; i++ {
+		a := a1 + d*float64(i)/float64(n)
+		xp, yp := disc(math.Cos(a), math.Sin(a), g)
+		x = append(x, xp)
+		y = append(y, yp)
+	}
+	return x, y
+}
+
+// ringccw reports whether a long/lat ring is counterclockwise, with its longitudes made continuous.
+// A ring going around a pole encloses that pole (the one nearer its mean latitude), as in SplitPolygon.
+func ringccw(x, y []float64) bool {
+	n := len(x)
+	ux := make([]float64, n)
+	uy := append([]float64{}, y...)
+	ux[0] = x[0]
+	for i := 1; i < n; i++ {
+		ux[i] = ux[i-1] + math.Remainder(x[i]-x[i-1], 360)
+	}
+	if math.Abs(ux[n-1]-ux[0]) > 180 {
+		pole := 90.0
+		if mean(uy) < 0 {
+			pole = -90
+		}
+		ux = append(ux, ux[n-1], ux[0])
+		uy = append(uy, pole, pole)
+	}
+	return planararea(ux, uy) > 0
+}
+
+// OrthoPoints maps long/lat points to the canvas, dropping those on the far hemisphere
+func OrthoPoints(x, y []float64, o Ortho, g Geometry) ([]float64, []float64) {
+	xp := []float64{}
+	yp := []float64{}
+	for i := 0; i < len(x) && i < len(y); i++ {
+		px, py, c := o.project(x[i], y[i])
+		if c < 0 {
+			continue
+		}
+		px, py = disc(px, py, g)
+		xp = append(xp, px)
+		yp = append(yp, py)
+	}
+	return xp, yp
+}
+
+// OrthoPolygon maps a long/lat polygon ring to the canvas, returning the visible rings.
+// Portions on the far hemisphere are clipped along great circles; each visible piece of the ring
+// is joined from where it leaves the horizon to the next place (going around the horizon in the
+// direction of the ring's winding) where a piece comes back, so a ring crossing the horizon
+// more than twice may make several rings.
+func OrthoPolygon(x, y []float64, o Ortho, g Geometry) []Path {
+	n := len(x)
+	if n < 3 || n != len(y) {
+		return nil
+	}
+	px := make([]float64, n)
+	py := make([]float64, n)
+	vis := make([]bool, n)
+	entry, nvis := -1, 0
+	for i := 0; i < n; i++ {
+		var c float64
+		px[i], py[i], c = o.project(x[i], y[i])
+		if vis[i] = c >= 0; vis[i] {
+			nvis++
+		}
+	}
+	for i := 0; i < n && entry < 0; i++ {
+		if !vis[i] && vis[(i+1)%n] {
+			entry = i
+		}
+	}
+	if nvis == 0 {
+		return nil
+	}
+	if entry < 0 { // all visible
+		var r Path
+		for i := 0; i < n; i++ {
+			cx, cy := disc(px[i], py[i], g)
+			r.X, r.Y = append(r.X, cx), append(r.Y, cy)
+		}
+		return []Path{r}
+	}
+	// the visible pieces, each from where it comes in at the horizon to where it goes out
+	type piece struct {
+		in, out float64
+		p       Path
+	}
+	pieces := []piece{}
+	var cur piece
+	for k := entry; k < entry+n; k++ {
+		i, j := k%n, (k+1)%n
+		if vis[i] == vis[j] {
+			if vis[j] {
+				cx, cy := disc(px[j], py[j], g)
+				cur.p.X, cur.p.Y = append(cur.p.X, cx), append(cur.p.Y, cy)
+			}
+			continue
+		}
+		a := o.horizon(x[i], y[i], x[j], y[j])
+		hx, hy := disc(math.Cos(a), math.Sin(a), g)
+		if vis[j] { // coming in
+			cur = piece{in: a, p: Path{X: []float64{hx}, Y: []float64{hy}}}
+			cx, cy := disc(px[j], py[j], g)
+			cur.p.X, cur.p.Y = append(cur.p.X, cx), append(cur.p.Y, cy)
+		} else { // going out
+			cur.out = a
+			cur.p.X, cur.p.Y = append(cur.p.X, hx), append(cur.p.Y, hy)
+			pieces = append(pieces, cur)
+		}
+	}
+	// link the pieces into rings along the horizon
+	ccw := ringccw(x, y)
+	turn := func(from, to float64) float64 { // the angle from one place on the horizon to another
+		d := math.Mod(to-from, 2*math.Pi)
+		if !ccw {
+			d = -d
+		}
+		if d < 0 {
+			d += 2 * math.Pi
+		}
+		return d
+	}
+	rings := []Path{}
+	used := make([]bool, len(pieces))
+	for first := range pieces {
+		if used[first] {
+			continue
+		}
+		var r Path
+		for k := first; ; {
+			used[k] = true
+			r.X, r.Y = append(r.X, pieces[k].p.X...), append(r.Y, pieces[k].p.Y...)
+			next := -1
+			for m := range pieces {
+				if (m == first || !used[m]) && (next < 0 || turn(pieces[k].out, pieces[m].in) < turn(pieces[k].out, pieces[next].in)) {
+					next = m
+				}
+			}
+			r.X, r.Y = arc(r.X, r.Y, pieces[k].out, pieces[next].in, ccw, g)
+			if next == first {
+				break
+			}
+			k = next
+		}
+		rings = append(rings, r)
+	}
+	return rings
+}
+
+// OrthoPolyline maps a long/lat line to the canvas, returning the visible pieces.
+// Each piece that reaches the far hemisphere ends at the horizon.
+func OrthoPolyline(x, y []float64, o Ortho, g Geometry) []Path {
+	n := len(x)
+	if n != len(y) {
+		return nil
+	}
+	paths := []Path{}
+	var cur Path
+	var pvis bool
+	for i := 0; i < n; i++ {
+		px, py, c := o.project(x[i], y[i])
+		vis := c >= 0
+		if i > 0 && vis != pvis {
+			a := o.horizon(x[i-1], y[i-1], x[i], y[i])
+			hx, hy := disc(math.Cos(a), math.Sin(a), g)
+			cur.X = append(cur.X, hx)
+			cur.Y = append(cur.Y, hy)
+			if pvis {
+				paths = append(paths, cur)
+				cur = Path{}
+			}
+		}
+		if vis {
+			cx, cy := disc(px, py, g)
+			cur.X = append(cur.X, cx)
+			cur.Y = append(cur.Y, cy)
+		}
+		pvis = vis
+	}
+	if len(cur.X) > 1 {
+		paths = append(paths, cur)
+	}
+	return paths
+}
+
+// OrthoHorizon returns the canvas coordinates of the horizon circle
+func OrthoHorizon(g Geometry) ([]float64, []float64) {
+	x := make([]float64, arcsteps+1)
+	y := make([]float64, arcsteps+1)
+	for i := 0; i <= arcsteps; i++ {
+		a := 2 * math.Pi * float64(i) / arcsteps
+		x[i], y[i] = disc(math.Cos(a), math.Sin(a), g)
+	}
+	return x, y
+}
+
+// Globe makes the disc of an orthographic view, filled with color (name:op)
+func Globe(g Geometry, color, style string) {
+	x, y := OrthoHorizon(g)
+	Deckshape("fill", style, x, y, 0, color, g)
+}
@@ -0,0 +1,68 @@
+package kml
+
+import (
+	"math"
+	"testing"
+)
+
+// pathArea returns the signed planar area of a ring
+func pathArea(r Path) float64 {
+	return planararea(r.X, r.Y)
+}
+
+func TestOrthoPolygonTwoCrossings(t *testing.T) {
+	g := Geometry{Xmin: -1, Xmax: 1, Ymin: -1, Ymax: 1}
+	// a square across the eastern horizon of a globe centered at 0,0
+	x := []float64{80, 100, 100, 80}
+	y := []float64{-10, -10, 10, 10}
+	rings := OrthoPolygon(x, y, Ortho{}, g)
+	if len(rings) != 1 {
+		t.Fatalf("%d rings, want 1", len(rings))
+	}
+	if a := pathArea(rings[0]); a <= 0 || a > 0.1 {
+		t.Errorf("area %v, want a small positive area", a)
+	}
+}
+
+func TestOrthoPolygonFourCrossings(t *testing.T) {
+	g := Geometry{Xmin: -1, Xmax: 1, Ymin: -1, Ymax: 1}
+	// a C shape on the far side, with two prongs reaching across the eastern horizon (long 90)
+	x := []float64{100, 150, 150, 70, 70, 100, 100, 70, 70}
+	y := []float64{-30, -30, 30, 30, 20, 20, -20, -20, -30}
+	for _, ccw := range []bool{true, false} {
+		rx, ry := x, y
+		if !ccw {
+			rx, ry = reversed(x), reversed(y)
+		}
+		rings := OrthoPolygon(rx, ry, Ortho{}, g)
+		if len(rings) != 2 {
+			t.Fatalf("ccw %v: %d rings, want 2", ccw, len(rings))
+		}
+		var total float64
+		for _, r := range rings {
+			total += math.Abs(pathArea(r))
+		}
+		// each prong is about a tenth of the way across the disc, and a sixth of its height
+		if total <= 0 || total > 0.2 {
+			t.Errorf("ccw %v: total area %v, want less than 0.2 (the disc is %v)", ccw, total, math.Pi)
+		}
+	}
+}
+
+func TestOrthoPolygonHidden(t *testing.T) {
+	g := Geometry{Xmin: -1, Xmax: 1, Ymin: -1, Ymax: 1}
+	x := []float64{170, 175, 175, 170}
+	y := []float64{0, 0, 5, 5}
+	if rings := OrthoPolygon(x, y, Ortho{}, g); len(rings) != 0 {
+		t.Errorf("%d rings on the far side, want 0", len(rings))
+	}
+}
+
+// reversed returns a reversed copy of a slice
+func reversed(v []float64) []float64 {
+	r := make([]float64, len(v))
+	for i := range v {
+		r[len(v)-1-i] = v[i]
+	}
+	return r
+}
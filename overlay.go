@@ -0,0 +1,373 @@
+package kml
+
+import (
+	"math"
+	"sort"
+)
+
+// overlayeps is the tolerance (relative to segment length) for treating
+// intersections as falling on an endpoint, and segments as collinear
+const overlayeps = 1e-9
+
+// overlaysnap is the grid (in degrees, about 0.1 mm) that points are rounded to in an overlay.
+// Without it, points that should be the same but were computed separately (like a corner of a
+// buffer strip and a vertex of a buffer circle, one bit apart) are distinct: the edges meeting there
+// are not linked into a ring, and the union of the buffer pieces of a line was empty.
+const overlaysnap = 1e-9
+
+// Overlay returns the result of a boolean operation on two sets of polygons (with holes):
+// "intersection" (the area in both), "union" (the area in either), "difference" (the area in a, but not b),
+// or "xor" (the area in one, but not both). The polygons in each set should not overlap each other,
+// although they may share borders.
+//
+// Edges are split where the two sets cross; each piece is kept or dropped depending on whether it is
+// inside the other set (pieces shared by both sets depend on which side the interiors are),
+// and the kept pieces are linked into rings.
+func Overlay(op string, a, b []Polygon) []Polygon {
+	segs := []*segment{}
+	for set, ps := range [][]Polygon{a, b} {
+		for _, e := range boundary(ps) {
+			e = edge{e.a.snap(), e.b.snap()}
+			if e.a != e.b {
+				segs = append(segs, &segment{e: e, set: set})
+			}
+		}
+	}
+	intersectAll(segs)
+
+	type fragment struct {
+		e   edge
+		set int
+	}
+	frags := []fragment{}
+	sets := map[edge]int{} // the sets having each piece, as a bit mask
+	byset := [2][]edge{}
+	for _, s := range segs {
+		for _, e := range s.pieces() {
+			frags = append(frags, fragment{e, s.set})
+			sets[e] |= 1 << s.set
+			byset[s.set] = append(byset[s.set], e)
+		}
+	}
+	index := [2]*bands{newbands(byset[0]), newbands(byset[1])}
+
+	result := []edge{}
+	done := map[edge]bool{}
+	for _, f := range frags {
+		other := 1 - f.set
+		rev := edge{f.e.b, f.e.a}
+		keep, reverse := false, false
+		switch {
+		case sets[f.e] == 3: // shared, with the interiors on the same side
+			if done[f.e] {
+				continue
+			}
+			done[f.e] = true
+			keep = op == "union" || op == "intersection"
+		case sets[rev]&(1<<other) != 0: // shared, with the interiors on opposite sides
+			keep = op == "difference" && f.set == 0
+		default:
+			in := index[other].inside((f.e.a.x+f.e.b.x)/2, (f.e.a.y+f.e.b.y)/2)
+			switch op {
+			case "union":
+				keep = !in
+			case "intersection":
+				keep = in
+			case "difference":
+				keep = (f.set == 0) != in
+				reverse = f.set == 1
+			case "xor":
+				keep, reverse = true, in
+			}
+		}
+		if !keep {
+			continue
+		}
+		if reverse {
+			result = append(result, rev)
+		} else {
+			result = append(result, f.e)
+		}
+	}
+	return assemble(result)
+}
+
+// Intersection returns the area common to two sets of polygons
+func Intersection(a, b []Polygon) []Polygon {
+	return Overlay("intersection", a, b)
+}
+
+// Union returns the area covered by either of two sets of polygons
+func Union(a, b []Polygon) []Polygon {
+	return Overlay("union", a, b)
+}
+
+// Difference returns the area of the first set of polygons not covered by the second
+func Difference(a, b []Polygon) []Polygon {
+	return Overlay("difference", a, b)
+}
+
+// Xor returns the area covered by one of two sets of polygons, but not both
+func Xor(a, b []Polygon) []Polygon {
+	return Overlay("xor", a, b)
+}
+
+// Keyhole returns a polygon as a single closed ring, with each hole joined to the outer boundary
+// by a cut of no width, so that it may be drawn as one filled shape.
+// The outer boundary goes counterclockwise, and the holes clockwise.
+func Keyhole(p Polygon) Path {
+	r := ringpoints(p.Outer, true)
+	if len(r) < 3 {
+		return p.Outer
+	}
+	holes := [][]point{}
+	for _, h := range p.Inner {
+		if hole := ringpoints(h, false); len(hole) >= 3 {
+			holes = append(holes, hole)
+		}
+	}
+	for k, hole := range holes {
+		// cut from the rightmost point of the hole to the nearest point of the ring,
+		// not crossing the ring or the other holes
+		m := 0
+		for i := range hole {
+			if hole[i].x > hole[m].x {
+				m = i
+			}
+		}
+		j := cut(r, hole[m], holes[k+1:])
+		joined := append([]point{}, r[:j+1]...)
+		for i := 0; i <= len(hole); i++ {
+			joined = append(joined, hole[(m+i)%len(hole)])
+		}
+		r = append(joined, r[j:]...)
+	}
+	var kr Path
+	for _, v := range append(r, r[0]) {
+		kr.X, kr.Y = append(kr.X, v.x), append(kr.Y, v.y)
+	}
+	return kr
+}
+
+// ringpoints returns the points of a ring (without repeating the first), counterclockwise if ccw is true
+func ringpoints(r Path, ccw bool) []point {
+	n := ringlen(r)
+	pts := make([]point, n)
+	for i := range pts {
+		pts[i] = point{r.X[i], r.Y[i]}
+	}
+	if n >= 3 && (planararea(r.X[:n], r.Y[:n]) > 0) != ccw {
+		for i, j := 0, n-1; i < j; i, j = i+1, j-1 {
+			pts[i], pts[j] = pts[j], pts[i]
+		}
+	}
+	return pts
+}
+
+// cut returns the index of the ring point nearest to p that may be joined to it without crossing
+// the ring or the holes (or the nearest point, if every cut crosses)
+func cut(r []point, p point, holes [][]point) int {
+	order := make([]int, len(r))
+	for i := range order {
+		order[i] = i
+	}
+	dist := func(i int) float64 { return math.Hypot(r[i].x-p.x, r[i].y-p.y) }
+	sort.Slice(order, func(a, b int) bool { return dist(order[a]) < dist(order[b]) })
+	crossed := func(q point, ring []point) bool {
+		for i := range ring {
+			a, b := ring[i], ring[(i+1)%len(ring)]
+			if segcross(p.x, p.y, q.x, q.y, a.x, a.y, b.x, b.y) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, i := range order {
+		ok := !crossed(r[i], r)
+		for _, h := range holes {
+			ok = ok && !crossed(r[i], h)
+		}
+		if ok {
+			return i
+		}
+	}
+	return order[0]
+}
+
+// snap rounds a point to the grid of overlaysnap, so that points computed separately
+// that should be the same (differing in the last bits) are
+func (p point) snap() point {
+	return point{math.Round(p.x/overlaysnap) * overlaysnap, math.Round(p.y/overlaysnap) * overlaysnap}
+}
+
+// segment is an edge from one of the sets in an overlay, with the points where it is split
+type segment struct {
+	e     edge
+	set   int
+	split []splitpoint
+}
+
+// splitpoint is a point along a segment, at t (0..1)
+type splitpoint struct {
+	t float64
+	p point
+}
+
+// pieces returns the edges between the split points of a segment
+func (s *segment) pieces() []edge {
+	sort.Slice(s.split, func(i, j int) bool { return s.split[i].t < s.split[j].t })
+	edges := []edge{}
+	a := s.e.a
+	for _, sp := range s.split {
+		if sp.p != a && sp.p != s.e.b {
+			edges = append(edges, edge{a, sp.p})
+			a = sp.p
+		}
+	}
+	return append(edges, edge{a, s.e.b})
+}
+
+// extent returns the bounds of a segment
+func (s *segment) extent() (float64, float64, float64, float64) {
+	return math.Min(s.e.a.x, s.e.b.x), math.Max(s.e.a.x, s.e.b.x), math.Min(s.e.a.y, s.e.b.y), math.Max(s.e.a.y, s.e.b.y)
+}
+
+// intersectAll splits the segments of each set where they meet segments of the other,
+// sweeping from left to right, so that only segments overlapping in x are compared
+func intersectAll(segs []*segment) {
+	order := make([]*segment, len(segs))
+	copy(order, segs)
+	sort.Slice(order, func(i, j int) bool {
+		xi, _, _, _ := order[i].extent()
+		xj, _, _, _ := order[j].extent()
+		return xi < xj
+	})
+	active := [2][]*segment{}
+	for _, s := range order {
+		x1, _, _, _ := s.extent()
+		other := 1 - s.set
+		kept := active[other][:0]
+		for _, o := range active[other] {
+			if _, x2, _, _ := o.extent(); x2 >= x1 {
+				kept = append(kept, o)
+				intersect(s, o)
+			}
+		}
+		active[other] = kept
+		active[s.set] = append(active[s.set], s)
+	}
+}
+
+// intersect adds split points where two segments cross, or where an endpoint of one
+// lies on the other. The same point is used for both, so the pieces meet exactly.
+func intersect(s, o *segment) {
+	sx1, sx2, sy1, sy2 := s.extent()
+	ox1, ox2, oy1, oy2 := o.extent()
+	if sy2 < oy1 || oy2 < sy1 || sx2 < ox1 || ox2 < sx1 {
+		return
+	}
+	p, q := s.e.a, o.e.a
+	rx, ry := s.e.b.x-p.x, s.e.b.y-p.y
+	ux, uy := o.e.b.x-q.x, o.e.b.y-q.y
+	qx, qy := q.x-p.x, q.y-p.y
+	rr, uu := rx*rx+ry*ry, ux*ux+uy*uy
+	denom := rx*uy - ry*ux
+	if math.Abs(denom) <= overlayeps*math.Sqrt(rr*uu) { // parallel
+		if math.Abs(qx*ry-qy*rx) > overlayeps*rr { // but not collinear
+			return
+		}
+		for _, v := range []point{o.e.a, o.e.b} {
+			if t := ((v.x-p.x)*rx + (v.y-p.y)*ry) / rr; t > overlayeps && t < 1-overlayeps {
+				s.split = append(s.split, splitpoint{t, v})
+			}
+		}
+		for _, v := range []point{s.e.a, s.e.b} {
+			if t := ((v.x-q.x)*ux + (v.y-q.y)*uy) / uu; t > overlayeps && t < 1-overlayeps {
+				o.split = append(o.split, splitpoint{t, v})
+			}
+		}
+		return
+	}
+	t := (qx*uy - qy*ux) / denom
+	w := (qx*ry - qy*rx) / denom
+	if t < -overlayeps || t > 1+overlayeps || w < -overlayeps || w > 1+overlayeps {
+		return
+	}
+	var v point
+	switch {
+	case t <= overlayeps:
+		v = s.e.a
+	case t >= 1-overlayeps:
+		v = s.e.b
+	case w <= overlayeps:
+		v = o.e.a
+	case w >= 1-overlayeps:
+		v = o.e.b
+	default:
+		v = point{p.x + t*rx, p.y + t*ry}
+	}
+	if t > overlayeps && t < 1-overlayeps {
+		s.split = append(s.split, splitpoint{t, v})
+	}
+	if w > overlayeps && w < 1-overlayeps {
+		o.split = append(o.split, splitpoint{w, v})
+	}
+}
+
+// bands indexes edges by horizontal bands, for point in polygon tests
+type bands struct {
+	y0, h float64
+	rows  [][]edge
+}
+
+// newbands makes an index of edges
+func newbands(edges []edge) *bands {
+	b := &bands{}
+	if len(edges) == 0 {
+		return b
+	}
+	miny, maxy := math.Inf(1), math.Inf(-1)
+	for _, e := range edges {
+		miny = math.Min(miny, math.Min(e.a.y, e.b.y))
+		maxy = math.Max(maxy, math.Max(e.a.y, e.b.y))
+	}
+	n := int(math.Sqrt(float64(len(edges)))) + 1
+	b.y0, b.h = miny, (maxy-miny)/float64(n)
+	if b.h == 0 {
+		b.h = 1
+	}
+	b.rows = make([][]edge, n)
+	for _, e := range edges {
+		r1, r2 := b.row(math.Min(e.a.y, e.b.y)), b.row(math.Max(e.a.y, e.b.y))
+		for r := r1; r <= r2; r++ {
+			b.rows[r] = append(b.rows[r], e)
+		}
+	}
+	return b
+}
+
+// row returns the band containing y
+func (b *bands) row(y float64) int {
+	r := int((y - b.y0) / b.h)
+	if r < 0 {
+		return 0
+	}
+	if r >= len(b.rows) {
+		return len(b.rows) - 1
+	}
+	return r
+}
+
+// inside reports whether a point is inside the indexed edges (even-odd rule)
+func (b *bands) inside(px, py float64) bool {
+	if len(b.rows) == 0 || py < b.y0 || py > b.y0+b.h*float64(len(b.rows)) {
+		return false
+	}
+	in := false
+	for _, e := range b.rows[b.row(py)] {
+		if (e.a.y > py) != (e.b.y > py) && px < (e.b.x-e.a.x)*(py-e.a.y)/(e.b.y-e.a.y)+e.a.x {
+			in = !in
+		}
+	}
+	return in
+}
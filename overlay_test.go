@@ -0,0 +1,98 @@
+package kml
+
+import (
+	"math"
+	"testing"
+)
+
+// square returns a closed counterclockwise ring with a corner at x, y
+func square(x, y, size float64) Path {
+	return Path{
+		X: []float64{x, x + size, x + size, x, x},
+		Y: []float64{y, y, y + size, y + size, y},
+	}
+}
+
+// planarPolygonArea returns the planar area of polygons, less their holes
+func planarPolygonArea(ps []Polygon) float64 {
+	var a float64
+	for _, p := range ps {
+		a += math.Abs(planararea(p.Outer.X, p.Outer.Y))
+		for _, h := range p.Inner {
+			a -= math.Abs(planararea(h.X, h.Y))
+		}
+	}
+	return a
+}
+
+func TestOverlayOverlappingSquares(t *testing.T) {
+	a := []Polygon{{Outer: square(0, 0, 2)}}
+	b := []Polygon{{Outer: square(1, 1, 2)}}
+	tests := []struct {
+		op    string
+		area  float64
+		count int
+	}{
+		{"union", 7, 1},
+		{"intersection", 1, 1},
+		{"difference", 3, 1},
+		{"xor", 6, 2},
+	}
+	for _, tt := range tests {
+		r := Overlay(tt.op, a, b)
+		if got := planarPolygonArea(r); math.Abs(got-tt.area) > 1e-9 {
+			t.Errorf("%s: area %v, want %v", tt.op, got, tt.area)
+		}
+		if len(r) != tt.count {
+			t.Errorf("%s: %d polygons, want %d", tt.op, len(r), tt.count)
+		}
+	}
+}
+
+func TestOverlayAdjacentSquares(t *testing.T) {
+	a := []Polygon{{Outer: square(0, 0, 1)}}
+	b := []Polygon{{Outer: square(1, 0, 1)}}
+	r := Union(a, b)
+	if len(r) != 1 {
+		t.Fatalf("union of adjacent squares: %d polygons, want 1", len(r))
+	}
+	if got := planarPolygonArea(r); math.Abs(got-2) > 1e-9 {
+		t.Errorf("union of adjacent squares: area %v, want 2", got)
+	}
+	if len(r[0].Inner) != 0 {
+		t.Errorf("union of adjacent squares: %d holes, want 0", len(r[0].Inner))
+	}
+	if got := Intersection(a, b); len(got) != 0 {
+		t.Errorf("intersection of adjacent squares: %d polygons, want 0", len(got))
+	}
+}
+
+func TestOverlayHoles(t *testing.T) {
+	outer := []Polygon{{Outer: square(0, 0, 4)}}
+	inner := []Polygon{{Outer: square(1, 1, 2)}}
+
+	holed := Difference(outer, inner)
+	if len(holed) != 1 || len(holed[0].Inner) != 1 {
+		t.Fatalf("difference: %d polygons, want 1 with 1 hole", len(holed))
+	}
+	if got := planarPolygonArea(holed); math.Abs(got-12) > 1e-9 {
+		t.Errorf("difference: area %v, want 12", got)
+	}
+
+	filled := Union(holed, inner)
+	if len(filled) != 1 || len(filled[0].Inner) != 0 {
+		t.Errorf("union filling the hole: %d polygons, want 1 with no holes", len(filled))
+	}
+	if got := planarPolygonArea(filled); math.Abs(got-16) > 1e-9 {
+		t.Errorf("union filling the hole: area %v, want 16", got)
+	}
+
+	// a square across the edge of the hole keeps only the part outside it
+	across := []Polygon{{Outer: square(2, 2, 2)}}
+	if got := planarPolygonArea(Intersection(holed, across)); math.Abs(got-3) > 1e-9 {
+		t.Errorf("intersection with a holed polygon: area %v, want 3", got)
+	}
+	if got := Intersection(holed, inner); len(got) != 0 {
+		t.Errorf("intersection with the hole: %d polygons, want 0", len(got))
+	}
+}
@@ -0,0 +1,135 @@
+package kml
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// palette is a named set of colors; continuous palettes are interpolated,
+// while qualitative palettes are used as is
+type palette struct {
+	kind   string // sequential, diverging, qualitative
+	colors []string
+}
+
+// palettes are ColorBrewer (colorbrewer2.org) sets and perceptual ramps (matplotlib)
+var palettes = map[string]palette{
+	// sequential
+	"Blues":   {"sequential", strings.Fields("#f7fbff #deebf7 #c6dbef #9ecae1 #6baed6 #4292c6 #2171b5 #08519c #08306b")},
+	"Greens":  {"sequential", strings.Fields("#f7fcf5 #e5f5e0 #c7e9c0 #a1d99b #74c476 #41ab5d #238b45 #006d2c #00441b")},
+	"Greys":   {"sequential", strings.Fields("#ffffff #f0f0f0 #d9d9d9 #bdbdbd #969696 #737373 #525252 #252525 #000000")},
+	"Oranges": {"sequential", strings.Fields("#fff5eb #fee6ce #fdd0a2 #fdae6b #fd8d3c #f16913 #d94801 #a63603 #7f2704")},
+	"Purples": {"sequential", strings.Fields("#fcfbfd #efedf5 #dadaeb #bcbddc #9e9ac8 #807dba #6a51a3 #54278f #3f007d")},
+	"Reds":    {"sequential", strings.Fields("#fff5f0 #fee0d2 #fcbba1 #fc9272 #fb6a4a #ef3b2c #cb181d #a50f15 #67000d")},
+	"BuGn":    {"sequential", strings.Fields("#f7fcfd #e5f5f9 #ccece6 #99d8c9 #66c2a4 #41ae76 #238b45 #006d2c #00441b")},
+	"BuPu":    {"sequential", strings.Fields("#f7fcfd #e0ecf4 #bfd3e6 #9ebcda #8c96c6 #8c6bb1 #88419d #810f7c #4d004b")},
+	"GnBu":    {"sequential", strings.Fields("#f7fcf0 #e0f3db #ccebc5 #a8ddb5 #7bccc4 #4eb3d3 #2b8cbe #0868ac #084081")},
+	"OrRd":    {"sequential", strings.Fields("#fff7ec #fee8c8 #fdd49e #fdbb84 #fc8d59 #ef6548 #d7301f #b30000 #7f0000")},
+	"PuBu":    {"sequential", strings.Fields("#fff7fb #ece7f2 #d0d1e6 #a6bddb #74a9cf #3690c0 #0570b0 #045a8d #023858")},
+	"PuRd":    {"sequential", strings.Fields("#f7f4f9 #e7e1ef #d4b9da #c994c7 #df65b0 #e7298a #ce1256 #980043 #67001f")},
+	"RdPu":    {"sequential", strings.Fields("#fff7f3 #fde0dd #fcc5c0 #fa9fb5 #f768a1 #dd3497 #ae017e #7a0177 #49006a")},
+	"YlGn":    {"sequential", strings.Fields("#ffffe5 #f7fcb9 #d9f0a3 #addd8e #78c679 #41ab5d #238443 #006837 #004529")},
+	"YlGnBu":  {"sequential", strings.Fields("#ffffd9 #edf8b1 #c7e9b4 #7fcdbb #41b6c4 #1d91c0 #225ea8 #253494 #081d58")},
+	"YlOrBr":  {"sequential", strings.Fields("#ffffe5 #fff7bc #fee391 #fec44f #fe9929 #ec7014 #cc4c02 #993404 #662506")},
+	"YlOrRd":  {"sequential", strings.Fields("#ffffcc #ffeda0 #fed976 #feb24c #fd8d3c #fc4e2a #e31a1c #bd0026 #800026")},
+	// perceptual
+	"viridis": {"sequential", strings.Fields("#440154 #482878 #3e4989 #31688e #26828e #1f9e89 #35b779 #6ece58 #b5de2b #fde725")},
+	"magma":   {"sequential", strings.Fields("#000004 #180f3d #440f76 #721f81 #9e2f7f #cd4071 #f1605d #fd9668 #feca8d #fcfdbf")},
+	"inferno": {"sequential", strings.Fields("#000004 #1b0c41 #4a0c6b #781c6d #a52c60 #cf4446 #ed6925 #fb9b06 #f7d13d #fcffa4")},
+	"plasma":  {"sequential", strings.Fields("#0d0887 #46039f #7201a8 #9c179e #bd3786 #d8576b #ed7953 #fb9f3a #fdca26 #f0f921")},
+	"cividis": {"sequential", strings.Fields("#00224e #123570 #3b496c #575d6d #707173 #8a8678 #a59c74 #c3b369 #e1cc55 #fee838")},
+	// diverging
+	"BrBG":     {"diverging", strings.Fields("#543005 #8c510a #bf812d #dfc27d #f6e8c3 #f5f5f5 #c7eae5 #80cdc1 #35978f #01665e #003c30")},
+	"PiYG":     {"diverging", strings.Fields("#8e0152 #c51b7d #de77ae #f1b6da #fde0ef #f7f7f7 #e6f5d0 #b8e186 #7fbc41 #4d9221 #276419")},
+	"PRGn":     {"diverging", strings.Fields("#40004b #762a83 #9970ab #c2a5cf #e7d4e8 #f7f7f7 #d9f0d3 #a6dba0 #5aae61 #1b7837 #00441b")},
+	"PuOr":     {"diverging", strings.Fields("#7f3b08 #b35806 #e08214 #fdb863 #fee0b6 #f7f7f7 #d8daeb #b2abd2 #8073ac #542788 #2d004b")},
+	"RdBu":     {"diverging", strings.Fields("#67001f #b2182b #d6604d #f4a582 #fddbc7 #f7f7f7 #d1e5f0 #92c5de #4393c3 #2166ac #053061")},
+	"RdGy":     {"diverging", strings.Fields("#67001f #b2182b #d6604d #f4a582 #fddbc7 #ffffff #e0e0e0 #bababa #878787 #4d4d4d #1a1a1a")},
+	"RdYlBu":   {"diverging", strings.Fields("#a50026 #d73027 #f46d43 #fdae61 #fee090 #ffffbf #e0f3f8 #abd9e9 #74add1 #4575b4 #313695")},
+	"RdYlGn":   {"diverging", strings.Fields("#a50026 #d73027 #f46d43 #fdae61 #fee08b #ffffbf #d9ef8b #a6d96a #66bd63 #1a9850 #006837")},
+	"Spectral": {"diverging", strings.Fields("#9e0142 #d53e4f #f46d43 #fdae61 #fee08b #ffffbf #e6f598 #abdda4 #66c2a5 #3288bd #5e4fa2")},
+	// qualitative
+	"Accent":  {"qualitative", strings.Fields("#7fc97f #beaed4 #fdc086 #ffff99 #386cb0 #f0027f #bf5b17 #666666")},
+	"Dark2":   {"qualitative", strings.Fields("#1b9e77 #d95f02 #7570b3 #e7298a #66a61e #e6ab02 #a6761d #666666")},
+	"Paired":  {"qualitative", strings.Fields("#a6cee3 #1f78b4 #b2df8a #33a02c #fb9a99 #e31a1c #fdbf6f #ff7f00 #cab2d6 #6a3d9a #ffff99 #b15928")},
+	"Pastel1": {"qualitative", strings.Fields("#fbb4ae #b3cde3 #ccebc5 #decbe4 #fed9a6 #ffffcc #e5d8bd #fddaec #f2f2f2")},
+	"Pastel2": {"qualitative", strings.Fields("#b3e2cd #fdcdac #cbd5e8 #f4cae4 #e6f5c9 #fff2ae #f1e2cc #cccccc")},
+	"Set1":    {"qualitative", strings.Fields("#e41a1c #377eb8 #4daf4a #984ea3 #ff7f00 #ffff33 #a65628 #f781bf #999999")},
+	"Set2":    {"qualitative", strings.Fields("#66c2a5 #fc8d62 #8da0cb #e78ac3 #a6d854 #ffd92f #e5c494 #b3b3b3")},
+	"Set3":    {"qualitative", strings.Fields("#8dd3c7 #ffffb3 #bebada #fb8072 #80b1d3 #fdb462 #b3de69 #fccde5 #d9d9d9 #bc80bd #ccebc5 #ffed6f")},
+}
+
+// Palettes returns the names of the built-in palettes of a kind
+// (sequential, diverging, qualitative, or "" for all)
+func Palettes(kind string) []string {
+	names := []string{}
+	for name, p := range palettes {
+		if kind == "" || p.kind == kind {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Palette returns n colors from a named palette; a name ending in "_r" reverses the palette.
+// Sequential and diverging palettes are interpolated to any number of colors,
+// while the colors of qualitative palettes are repeated if more are needed than it has.
+func Palette(name string, n int) ([]string, error) {
+	reverse := strings.HasSuffix(name, "_r")
+	p, ok := palettes[strings.TrimSuffix(name, "_r")]
+	if !ok {
+		return nil, fmt.Errorf("%q: unknown palette", name)
+	}
+	if n < 1 {
+		return nil, fmt.Errorf("%d: the number of colors must be positive", n)
+	}
+	colors := make([]string, n)
+	if p.kind == "qualitative" {
+		for i := range colors {
+			colors[i] = p.colors[i%len(p.colors)]
+		}
+	} else {
+		for i := range colors {
+			t := 0.5
+			if n > 1 {
+				t = float64(i) / float64(n-1)
+			}
+			colors[i] = interpolate(p.colors, t)
+		}
+	}
+	if reverse {
+		for i, j := 0, n-1; i < j; i, j = i+1, j-1 {
+			colors[i], colors[j] = colors[j], colors[i]
+		}
+	}
+	return colors, nil
+}
+
+// interpolate returns the color at t (0..1) along evenly spaced colors
+func interpolate(colors []string, t float64) string {
+	last := len(colors) - 1
+	f := t * float64(last)
+	i := int(f)
+	if i >= last {
+		return colors[last]
+	}
+	a, _ := parseRGB(colors[i])
+	b, _ := parseRGB(colors[i+1])
+	return lerp(a, b, f-float64(i)).String()
+}
+
+// Ramp returns n colors given either a palette name, or two colors to interpolate between
+// (separated by space, like "#eff3ff #08519c")
+func Ramp(spec string, n int) ([]string, error) {
+	f := strings.Fields(spec)
+	switch len(f) {
+	case 1:
+		return Palette(f[0], n)
+	case 2:
+		return ColorRamp(f[0], f[1], n)
+	default:
+		return nil, fmt.Errorf("%q: ramp must be a palette name or two colors", spec)
+	}
+}
@@ -0,0 +1,46 @@
+package kml
+
+import "testing"
+
+func TestPalette(t *testing.T) {
+	tests := []struct {
+		name  string
+		n     int
+		first string
+		last  string
+	}{
+		{"Blues", 9, "#f7fbff", "#08306b"},
+		{"Blues_r", 9, "#08306b", "#f7fbff"},
+		{"Set1", 10, "#e41a1c", "#e41a1c"},
+	}
+	for _, tt := range tests {
+		colors, err := Palette(tt.name, tt.n)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(colors) != tt.n {
+			t.Errorf("%s: %d colors, want %d", tt.name, len(colors), tt.n)
+			continue
+		}
+		if colors[0] != tt.first || colors[tt.n-1] != tt.last {
+			t.Errorf("%s: colors from %s to %s, want %s to %s", tt.name, colors[0], colors[tt.n-1], tt.first, tt.last)
+		}
+	}
+}
+
+func TestPaletteErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+	}{
+		{"Blues", 0},
+		{"Set1_r", -1},
+		{"NoSuchPalette", 5},
+	}
+	for _, tt := range tests {
+		if colors, err := Palette(tt.name, tt.n); err == nil {
+			t.Errorf("%s, %d: %v, want an error", tt.name, tt.n, colors)
+		}
+	}
+}
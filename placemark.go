@@ -0,0 +1,195 @@
+package kml
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Polygon is an outer boundary with optional holes, in long/lat
+type Polygon struct {
+	Outer Path
+	Inner []Path
+}
+
+// Placemark is a named set of polygons with attribute (ExtendedData) values
+type Placemark struct {
+	Name     string
+	Data     map[string]string
+	Polygons []Polygon
+}
+
+// NewPolygon makes a polygon from KML coordinate strings for the outer boundary and holes
+func NewPolygon(outer string, inner ...string) Polygon {
+	var p Polygon
+	p.Outer.X, p.Outer.Y = ParsePlainCoords(outer)
+	for _, s := range inner {
+		var h Path
+		h.X, h.Y = ParsePlainCoords(s)
+		if len(h.X) > 0 {
+			p.Inner = append(p.Inner, h)
+		}
+	}
+	return p
+}
+
+// Attr returns the value of a placemark attribute; "name" is the placemark name,
+// unless there is data of that name
+func (pm Placemark) Attr(name string) string {
+	if v, ok := pm.Data[name]; ok {
+		return v
+	}
+	if name == "name" {
+		return pm.Name
+	}
+	return ""
+}
+
+// kmlpolygon is the KML markup of a polygon
+type kmlpolygon struct {
+	Outer string   `xml:"outerBoundaryIs>LinearRing>coordinates"`
+	Inner []string `xml:"innerBoundaryIs>LinearRing>coordinates"`
+}
+
+// kmlplacemark is the KML markup of a placemark
+type kmlplacemark struct {
+	Name string `xml:"name"`
+	Data []struct {
+		Name string `xml:"name,attr"`
+		Text string `xml:",chardata"`
+	} `xml:"ExtendedData>SchemaData>SimpleData"`
+	Polygon       []kmlpolygon `xml:"Polygon"`
+	MultiGeometry []kmlpolygon `xml:"MultiGeometry>Polygon"`
+}
+
+// ReadPlacemarks reads the polygon placemarks of a KML document,
+// either directly in the Document, or in a Folder
+func ReadPlacemarks(r io.Reader) ([]Placemark, error) {
+	var doc struct {
+		Placemark []kmlplacemark `xml:"Document>Placemark"`
+		Folder    []kmlplacemark `xml:"Document>Folder>Placemark"`
+	}
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	pms := []Placemark{}
+	for _, k := range append(doc.Placemark, doc.Folder...) {
+		pm := Placemark{Name: k.Name, Data: map[string]string{}}
+		for _, d := range k.Data {
+			pm.Data[d.Name] = d.Text
+		}
+		for _, p := range append(k.Polygon, k.MultiGeometry...) {
+			pm.Polygons = append(pm.Polygons, NewPolygon(p.Outer, p.Inner...))
+		}
+		pms = append(pms, pm)
+	}
+	return pms, nil
+}
+
+// WriteKML writes placemarks as a KML document, which may be read by ReadPlacemarks
+func WriteKML(w io.Writer, pms []Placemark) error {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString("<kml xmlns=\"http://www.opengis.net/kml/2.2\">\n<Document>\n")
+	for _, pm := range pms {
+		b.WriteString("<Placemark>\n<name>")
+		xml.EscapeText(&b, []byte(pm.Name))
+		b.WriteString("</name>\n")
+		if len(pm.Data) > 0 {
+			b.WriteString("<ExtendedData><SchemaData>\n")
+			for _, k := range sortedkeys(pm.Data) {
+				fmt.Fprintf(&b, "<SimpleData name=%q>", k)
+				xml.EscapeText(&b, []byte(pm.Data[k]))
+				b.WriteString("</SimpleData>\n")
+			}
+			b.WriteString("</SchemaData></ExtendedData>\n")
+		}
+		if len(pm.Polygons) > 1 {
+			b.WriteString("<MultiGeometry>\n")
+		}
+		for _, p := range pm.Polygons {
+			fmt.Fprintf(&b, "<Polygon><outerBoundaryIs><LinearRing><coordinates>%s</coordinates></LinearRing></outerBoundaryIs>", kmlcoords(p.Outer))
+			for _, h := range p.Inner {
+				fmt.Fprintf(&b, "<innerBoundaryIs><LinearRing><coordinates>%s</coordinates></LinearRing></innerBoundaryIs>", kmlcoords(h))
+			}
+			b.WriteString("</Polygon>\n")
+		}
+		if len(pm.Polygons) > 1 {
+			b.WriteString("</MultiGeometry>\n")
+		}
+		b.WriteString("</Placemark>\n")
+	}
+	b.WriteString("</Document>\n</kml>\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// WriteGeoJSON writes placemarks as a GeoJSON FeatureCollection, with the name and attributes as properties
+func WriteGeoJSON(w io.Writer, pms []Placemark) error {
+	type geometry struct {
+		Type        string      `json:"type"`
+		Coordinates interface{} `json:"coordinates"`
+	}
+	type feature struct {
+		Type       string            `json:"type"`
+		Properties map[string]string `json:"properties"`
+		Geometry   geometry          `json:"geometry"`
+	}
+	fc := struct {
+		Type     string    `json:"type"`
+		Features []feature `json:"features"`
+	}{Type: "FeatureCollection", Features: []feature{}}
+	for _, pm := range pms {
+		props := map[string]string{"name": pm.Name}
+		for k, v := range pm.Data {
+			props[k] = v
+		}
+		polys := [][][][2]float64{}
+		for _, p := range pm.Polygons {
+			rings := [][][2]float64{geocoords(p.Outer)}
+			for _, h := range p.Inner {
+				rings = append(rings, geocoords(h))
+			}
+			polys = append(polys, rings)
+		}
+		g := geometry{Type: "MultiPolygon", Coordinates: polys}
+		if len(polys) == 1 {
+			g = geometry{Type: "Polygon", Coordinates: polys[0]}
+		}
+		fc.Features = append(fc.Features, feature{Type: "Feature", Properties: props, Geometry: g})
+	}
+	return json.NewEncoder(w).Encode(fc)
+}
+
+// kmlcoords formats a path as KML coordinates
+func kmlcoords(p Path) string {
+	s := make([]string, len(p.X))
+	for i := range p.X {
+		s[i] = strconv.FormatFloat(p.X[i], 'f', -1, 64) + "," + strconv.FormatFloat(p.Y[i], 'f', -1, 64)
+	}
+	return strings.Join(s, " ")
+}
+
+// geocoords returns a path as GeoJSON positions
+func geocoords(p Path) [][2]float64 {
+	c := make([][2]float64, len(p.X))
+	for i := range p.X {
+		c[i] = [2]float64{p.X[i], p.Y[i]}
+	}
+	return c
+}
+
+// sortedkeys returns the keys of a map in order
+func sortedkeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
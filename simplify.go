@@ -0,0 +1,143 @@
+package kml
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Simplify reduces the number of points in a line or polygon ring, using
+// the named method: "dp" (Douglas–Peucker, the default) or "vw" (Visvalingam–Whyatt).
+// The tolerance is in the same units as the coordinates: degrees for raw coordinates,
+// or canvas units for mapped coordinates. The first and last points are always kept.
+func Simplify(method string, x, y []float64, tol float64) ([]float64, []float64) {
+	if tol <= 0 || len(x) != len(y) {
+		return x, y
+	}
+	switch method {
+	case "vw", "visvalingam":
+		return SimplifyVW(x, y, tol)
+	default:
+		return SimplifyDP(x, y, tol)
+	}
+}
+
+// segdist returns the distance from a point to the segment x1,y1 - x2,y2
+func segdist(px, py, x1, y1, x2, y2 float64) float64 {
+	dx, dy := x2-x1, y2-y1
+	d := dx*dx + dy*dy
+	if d == 0 {
+		return math.Hypot(px-x1, py-y1)
+	}
+	t := ((px-x1)*dx + (py-y1)*dy) / d
+	t = math.Max(0, math.Min(1, t))
+	return math.Hypot(px-(x1+t*dx), py-(y1+t*dy))
+}
+
+// SimplifyDP simplifies using the Douglas–Peucker algorithm:
+// points closer than tol to the simplified line are removed.
+func SimplifyDP(x, y []float64, tol float64) ([]float64, []float64) {
+	n := len(x)
+	if n < 3 || n != len(y) {
+		return x, y
+	}
+	keep := make([]bool, n)
+	keep[0], keep[n-1] = true, true
+	stack := [][2]int{{0, n - 1}}
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		first, last := s[0], s[1]
+		index, dmax := -1, tol
+		for i := first + 1; i < last; i++ {
+			if d := segdist(x[i], y[i], x[first], y[first], x[last], y[last]); d > dmax {
+				index, dmax = i, d
+			}
+		}
+		if index > 0 {
+			keep[index] = true
+			stack = append(stack, [2]int{first, index}, [2]int{index, last})
+		}
+	}
+	return kept(x, y, keep)
+}
+
+// kept makes new slices from the points marked to keep
+func kept(x, y []float64, keep []bool) ([]float64, []float64) {
+	xp := []float64{}
+	yp := []float64{}
+	for i, k := range keep {
+		if k {
+			xp = append(xp, x[i])
+			yp = append(yp, y[i])
+		}
+	}
+	return xp, yp
+}
+
+// triarea returns the area of a triangle
+func triarea(x1, y1, x2, y2, x3, y3 float64) float64 {
+	return math.Abs((x2-x1)*(y3-y1)-(x3-x1)*(y2-y1)) / 2
+}
+
+// vwpoint is a point in the Visvalingam–Whyatt queue
+type vwpoint struct {
+	i, prev, next, index int
+	area                 float64
+}
+
+// vwqueue orders points by effective area
+type vwqueue []*vwpoint
+
+func (q vwqueue) Len() int            { return len(q) }
+func (q vwqueue) Less(i, j int) bool  { return q[i].area < q[j].area }
+func (q vwqueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i]; q[i].index = i; q[j].index = j }
+func (q *vwqueue) Push(v interface{}) { p := v.(*vwpoint); p.index = len(*q); *q = append(*q, p) }
+func (q *vwqueue) Pop() interface{} {
+	old := *q
+	p := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return p
+}
+
+// SimplifyVW simplifies using the Visvalingam–Whyatt algorithm:
+// points whose effective triangle area is less than tol*tol are removed.
+func SimplifyVW(x, y []float64, tol float64) ([]float64, []float64) {
+	n := len(x)
+	if n < 3 || n != len(y) {
+		return x, y
+	}
+	min := tol * tol
+	pts := make([]*vwpoint, n)
+	q := vwqueue{}
+	for i := 0; i < n; i++ {
+		pts[i] = &vwpoint{i: i, prev: i - 1, next: i + 1, area: math.Inf(1)}
+		if i > 0 && i < n-1 {
+			pts[i].area = triarea(x[i-1], y[i-1], x[i], y[i], x[i+1], y[i+1])
+			heap.Push(&q, pts[i])
+		}
+	}
+	keep := make([]bool, n)
+	for i := range keep {
+		keep[i] = true
+	}
+	update := func(p *vwpoint, floor float64) {
+		if p.prev < 0 || p.next >= n {
+			return
+		}
+		a := triarea(x[p.prev], y[p.prev], x[p.i], y[p.i], x[p.next], y[p.next])
+		p.area = math.Max(a, floor) // effective areas never decrease
+		heap.Fix(&q, p.index)
+	}
+	for q.Len() > 0 {
+		p := heap.Pop(&q).(*vwpoint)
+		if p.area >= min {
+			break
+		}
+		keep[p.i] = false
+		prev, next := pts[p.prev], pts[p.next]
+		prev.next, next.prev = p.next, p.prev
+		update(prev, p.area)
+		update(next, p.area)
+	}
+	return kept(x, y, keep)
+}
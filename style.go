@@ -0,0 +1,104 @@
+package kml
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Style is how to draw a placemark: empty values leave the current setting alone
+type Style struct {
+	Fill      string  `json:"fill"`      // fill color ("none" for no fill)
+	Stroke    string  `json:"stroke"`    // outline color ("none" for no outline)
+	LineWidth float64 `json:"linewidth"` // outline width
+	Opacity   float64 `json:"opacity"`   // opacity (percent) of colors without one
+	Label     string  `json:"label"`     // attribute to label with ("none" for no label)
+	TextSize  float64 `json:"textsize"`  // label size
+	TextColor string  `json:"textcolor"` // label color
+}
+
+// Rule is a style for the placemarks that match a filter expression (see Where);
+// an empty expression matches every placemark
+type Rule struct {
+	Where string `json:"where"`
+	Style
+	match func(Placemark) bool
+}
+
+// ReadRules reads styling rules from a JSON array like:
+//
+//	[
+//	  {"fill": "lightgray", "stroke": "white", "linewidth": 0.05},
+//	  {"where": "iso3 == \"FRA\"", "fill": "red", "textsize": 1, "textcolor": "maroon"},
+//	  {"where": "continent != \"Europe\"", "opacity": 30, "label": "none"}
+//	]
+func ReadRules(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	if err := json.NewDecoder(r).Decode(&rules); err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		if strings.TrimSpace(rules[i].Where) == "" {
+			continue
+		}
+		m, err := Where(rules[i].Where)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %v", i+1, err)
+		}
+		rules[i].match = m
+	}
+	return rules, nil
+}
+
+// Apply returns the style of a placemark: the base style, overridden
+// by every matching rule in order (so later rules take precedence)
+func Apply(rules []Rule, pm Placemark, base Style) Style {
+	s := base
+	for _, r := range rules {
+		if r.match != nil && !r.match(pm) {
+			continue
+		}
+		s = s.override(r.Style)
+	}
+	if s.Opacity > 0 {
+		s.Fill = withop(s.Fill, s.Opacity)
+		s.Stroke = withop(s.Stroke, s.Opacity)
+	}
+	return s
+}
+
+// override replaces settings with those specified in o
+func (s Style) override(o Style) Style {
+	if o.Fill != "" {
+		s.Fill = o.Fill
+	}
+	if o.Stroke != "" {
+		s.Stroke = o.Stroke
+	}
+	if o.LineWidth > 0 {
+		s.LineWidth = o.LineWidth
+	}
+	if o.Opacity > 0 {
+		s.Opacity = o.Opacity
+	}
+	if o.Label != "" {
+		s.Label = o.Label
+	}
+	if o.TextSize > 0 {
+		s.TextSize = o.TextSize
+	}
+	if o.TextColor != "" {
+		s.TextColor = o.TextColor
+	}
+	return s
+}
+
+// withop adds an opacity to a color that does not have one
+func withop(color string, op float64) string {
+	if color == "" || color == "none" || strings.Contains(color, ":") {
+		return color
+	}
+	return color + ":" + strconv.FormatFloat(op, 'f', -1, 64)
+}
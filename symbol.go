@@ -0,0 +1,53 @@
+package kml
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// SymbolSizes scales values to symbol diameters, so that the area of each symbol is proportional
+// to its value (from zero): the largest value has diameter max, and no symbol is smaller than min.
+func SymbolSizes(v []float64, min, max float64) []float64 {
+	sizes := make([]float64, len(v))
+	vmax := 0.0
+	for _, x := range v {
+		vmax = math.Max(vmax, math.Abs(x))
+	}
+	for i, x := range v {
+		d := max
+		if vmax > 0 {
+			d = max * math.Sqrt(math.Abs(x)/vmax)
+		}
+		sizes[i] = math.Max(d, min)
+	}
+	return sizes
+}
+
+// DeckSymbols makes deck or decksh markup for proportional circles, given their canvas
+// positions, diameters and colors (one for each symbol, or one for all).
+// Larger symbols are drawn first, so that smaller ones are not hidden.
+func DeckSymbols(style string, x, y, sizes []float64, colors []string) {
+	n := len(x)
+	if n != len(y) || n != len(sizes) || len(colors) == 0 {
+		return
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return sizes[order[i]] > sizes[order[j]] })
+	for _, i := range order {
+		color := colors[0]
+		if i < len(colors) {
+			color = colors[i]
+		}
+		fill, op := colorop(color)
+		switch style {
+		case "deck":
+			fmt.Printf(dotfmt, x[i], y[i], sizes[i], fill, op)
+		case "decksh":
+			fmt.Printf(dshdotfmt, x[i], y[i], sizes[i], fill, op)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package kml
+
+import "math"
+
+// point is a map key for an exact coordinate
+type point struct {
+	x, y float64
+}
+
+// SimplifyShared simplifies the polygons of a set of placemarks, keeping shared borders intact.
+// Rings are cut into arcs where borders meet (points with more than two distinct neighbors);
+// each arc is simplified once, so adjacent polygons still meet exactly.
+// Method and tolerance are as in Simplify.
+func SimplifyShared(method string, pms []Placemark, tol float64) []Placemark {
+	if tol <= 0 {
+		return pms
+	}
+	// find the neighbors of every point
+	nb := map[point]map[point]bool{}
+	link := func(a, b point) {
+		if a == b {
+			return
+		}
+		if nb[a] == nil {
+			nb[a] = map[point]bool{}
+		}
+		nb[a][b] = true
+	}
+	eachRing(pms, func(r *Path) {
+		n := ringlen(*r)
+		for i := 0; i < n; i++ {
+			j := (i + 1) % n
+			a, b := point{r.X[i], r.Y[i]}, point{r.X[j], r.Y[j]}
+			link(a, b)
+			link(b, a)
+		}
+	})
+	// simplify each arc once, rebuilding the rings
+	done := map[string]Path{}
+	out := make([]Placemark, len(pms))
+	for i, pm := range pms {
+		out[i] = pm
+		out[i].Polygons = make([]Polygon, len(pm.Polygons))
+		for j, p := range pm.Polygons {
+			np := Polygon{Outer: simplifyRing(method, p.Outer, tol, nb, done)}
+			for _, h := range p.Inner {
+				np.Inner = append(np.Inner, simplifyRing(method, h, tol, nb, done))
+			}
+			out[i].Polygons[j] = np
+		}
+	}
+	return out
+}
+
+// eachRing calls f for every ring in a set of placemarks
+func eachRing(pms []Placemark, f func(*Path)) {
+	for i := range pms {
+		for j := range pms[i].Polygons {
+			f(&pms[i].Polygons[j].Outer)
+			for k := range pms[i].Polygons[j].Inner {
+				f(&pms[i].Polygons[j].Inner[k])
+			}
+		}
+	}
+}
+
+// ringlen returns the number of distinct points in a ring, not counting a repeated closing point
+func ringlen(r Path) int {
+	n := len(r.X)
+	if n > 1 && r.X[0] == r.X[n-1] && r.Y[0] == r.Y[n-1] {
+		n--
+	}
+	return n
+}
+
+// less orders points
+func less(a, b point) bool {
+	return a.x < b.x || (a.x == b.x && a.y < b.y)
+}
+
+// simplifyRing cuts a ring into arcs at junctions, simplifies the arcs (using the cache),
+// and joins them into a closed ring
+func simplifyRing(method string, r Path, tol float64, nb map[point]map[point]bool, done map[string]Path) Path {
+	n := ringlen(r)
+	if n < 3 {
+		return r
+	}
+	pts := make([]point, n)
+	junctions := []int{}
+	start := 0
+	for i := 0; i < n; i++ {
+		pts[i] = point{r.X[i], r.Y[i]}
+		if len(nb[pts[i]]) > 2 {
+			junctions = append(junctions, i)
+		}
+		if less(pts[i], pts[start]) {
+			start = i
+		}
+	}
+	// a ring without junctions is a single arc, starting at its least point
+	if len(junctions) == 0 {
+		junctions = []int{start}
+	}
+	res := Path{}
+	for k, s := range junctions {
+		e := junctions[0] + n
+		if k < len(junctions)-1 {
+			e = junctions[k+1]
+		}
+		arc := make([]point, 0, e-s+1)
+		for i := s; i <= e; i++ {
+			arc = append(arc, pts[i%n])
+		}
+		a := simplifyArc(method, arc, tol, done)
+		if len(res.X) > 0 {
+			a.X, a.Y = a.X[1:], a.Y[1:]
+		}
+		res.X = append(res.X, a.X...)
+		res.Y = append(res.Y, a.Y...)
+	}
+	return res
+}
+
+// simplifyArc simplifies an arc in its canonical direction (the one with the lesser end first),
+// so that the same arc shared by two rings, in either direction, gives the same result
+func simplifyArc(method string, arc []point, tol float64, done map[string]Path) Path {
+	rev := false
+	for i, j := 0, len(arc)-1; i < j; i, j = i+1, j-1 {
+		if arc[i] != arc[j] {
+			rev = less(arc[j], arc[i])
+			break
+		}
+	}
+	n := len(arc)
+	x := make([]float64, n)
+	y := make([]float64, n)
+	for i, p := range arc {
+		if rev {
+			p = arc[n-1-i]
+		}
+		x[i], y[i] = p.x, p.y
+	}
+	key := arckey(x, y)
+	p, ok := done[key]
+	if !ok {
+		p.X, p.Y = Simplify(method, x, y, tol)
+		done[key] = p
+	}
+	if !rev {
+		return Path{X: append([]float64{}, p.X...), Y: append([]float64{}, p.Y...)}
+	}
+	m := len(p.X)
+	r := Path{X: make([]float64, m), Y: make([]float64, m)}
+	for i := 0; i < m; i++ {
+		r.X[i], r.Y[i] = p.X[m-1-i], p.Y[m-1-i]
+	}
+	return r
+}
+
+// arckey makes a cache key from the coordinates of an arc
+func arckey(x, y []float64) string {
+	b := make([]byte, 0, len(x)*16)
+	for i := range x {
+		b = appendFloat(b, x[i])
+		b = appendFloat(b, y[i])
+	}
+	return string(b)
+}
+
+// appendFloat appends the bits of a float
+func appendFloat(b []byte, f float64) []byte {
+	u := math.Float64bits(f)
+	for i := 0; i < 8; i++ {
+		b = append(b, byte(u>>(8*i)))
+	}
+	return b
+}
@@ -0,0 +1,268 @@
+package kml
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Where parses a filter expression, returning a function that reports whether a placemark matches.
+// Expressions compare attributes (the placemark name or its data, by name) with quoted strings,
+// numbers or other attributes, and combine comparisons with &&, || and ! and parentheses:
+//
+//	continent == "Africa" && status == "Member State"
+//	STUSPS == "TX" || (ALAND > 1e11 && !(NAME ~ "^New"))
+//
+// Comparison operators are ==, !=, <, <=, >, >= and ~ (regular expression match).
+// Values are compared as numbers if both are numeric, otherwise as strings.
+// An attribute alone is true if it is not empty.
+func Where(expr string) (func(Placemark) bool, error) {
+	toks, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	f, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("%q: unexpected %q", expr, p.toks[p.pos].text)
+	}
+	return f, nil
+}
+
+// Filter returns the placemarks that match
+func Filter(pms []Placemark, match func(Placemark) bool) []Placemark {
+	result := []Placemark{}
+	for _, pm := range pms {
+		if match(pm) {
+			result = append(result, pm)
+		}
+	}
+	return result
+}
+
+// token kinds
+const (
+	tokIdent = iota
+	tokString
+	tokNumber
+	tokOp
+)
+
+type token struct {
+	kind int
+	text string
+}
+
+// tokenize splits an expression into identifiers, strings, numbers and operators
+func tokenize(s string) ([]token, error) {
+	toks := []token{}
+	r := []rune(s)
+	for i := 0; i < len(r); {
+		c := r[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			j := i + 1
+			var b strings.Builder
+			for ; j < len(r) && r[j] != c; j++ {
+				if r[j] == '\\' && j+1 < len(r) {
+					j++
+				}
+				b.WriteRune(r[j])
+			}
+			if j >= len(r) {
+				return nil, fmt.Errorf("%q: unterminated string", s)
+			}
+			toks = append(toks, token{tokString, b.String()})
+			i = j + 1
+		case unicode.IsDigit(c) || c == '.' || (c == '-' && i+1 < len(r) && unicode.IsDigit(r[i+1])):
+			j := i + 1
+			for j < len(r) && (unicode.IsDigit(r[j]) || strings.ContainsRune(".eE", r[j]) ||
+				((r[j] == '-' || r[j] == '+') && (r[j-1] == 'e' || r[j-1] == 'E'))) {
+				j++
+			}
+			toks = append(toks, token{tokNumber, string(r[i:j])})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i + 1
+			for j < len(r) && (unicode.IsLetter(r[j]) || unicode.IsDigit(r[j]) || r[j] == '_' || r[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{tokIdent, string(r[i:j])})
+			i = j
+		default:
+			op := ""
+			for _, o := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "~", "(", ")"} {
+				if strings.HasPrefix(string(r[i:]), o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("%q: unexpected %q", s, c)
+			}
+			toks = append(toks, token{tokOp, op})
+			i += len(op)
+		}
+	}
+	return toks, nil
+}
+
+// parser is a recursive descent parser for filter expressions
+type parser struct {
+	toks []token
+	pos  int
+}
+
+// next reports whether the next token is the operator op, consuming it if so
+func (p *parser) next(op string) bool {
+	if p.pos < len(p.toks) && p.toks[p.pos].kind == tokOp && p.toks[p.pos].text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// or parses: and { "||" and }
+func (p *parser) or() (func(Placemark) bool, error) {
+	f, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.next("||") {
+		g, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		a := f
+		f = func(pm Placemark) bool { return a(pm) || g(pm) }
+	}
+	return f, nil
+}
+
+// and parses: unary { "&&" unary }
+func (p *parser) and() (func(Placemark) bool, error) {
+	f, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.next("&&") {
+		g, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		a := f
+		f = func(pm Placemark) bool { return a(pm) && g(pm) }
+	}
+	return f, nil
+}
+
+// unary parses: "!" unary | "(" or ")" | comparison
+func (p *parser) unary() (func(Placemark) bool, error) {
+	if p.next("!") {
+		f, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(pm Placemark) bool { return !f(pm) }, nil
+	}
+	if p.next("(") {
+		f, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.next(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		return f, nil
+	}
+	return p.comparison()
+}
+
+// comparison parses: operand [ op operand ]
+func (p *parser) comparison() (func(Placemark) bool, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos >= len(p.toks) || p.toks[p.pos].kind != tokOp {
+		return func(pm Placemark) bool { return left(pm) != "" }, nil
+	}
+	op := p.toks[p.pos].text
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=", "~":
+		p.pos++
+	default:
+		return func(pm Placemark) bool { return left(pm) != "" }, nil
+	}
+	if op == "~" {
+		if p.pos >= len(p.toks) || p.toks[p.pos].kind != tokString {
+			return nil, fmt.Errorf("~ needs a quoted regular expression")
+		}
+		re, err := regexp.Compile(p.toks[p.pos].text)
+		if err != nil {
+			return nil, err
+		}
+		p.pos++
+		return func(pm Placemark) bool { return re.MatchString(left(pm)) }, nil
+	}
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	return func(pm Placemark) bool { return compare(left(pm), right(pm), op) }, nil
+}
+
+// operand parses an attribute name, string or number
+func (p *parser) operand() (func(Placemark) string, error) {
+	if p.pos >= len(p.toks) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	t := p.toks[p.pos]
+	p.pos++
+	switch t.kind {
+	case tokIdent:
+		return func(pm Placemark) string { return pm.Attr(t.text) }, nil
+	case tokString, tokNumber:
+		return func(Placemark) string { return t.text }, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+// compare compares two values, as numbers if both are numeric
+func compare(a, b, op string) bool {
+	c := strings.Compare(a, b)
+	x, xerr := strconv.ParseFloat(strings.TrimSpace(a), 64)
+	y, yerr := strconv.ParseFloat(strings.TrimSpace(b), 64)
+	if xerr == nil && yerr == nil {
+		switch {
+		case x < y:
+			c = -1
+		case x > y:
+			c = 1
+		default:
+			c = 0
+		}
+	}
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}